@@ -0,0 +1,52 @@
+package benchmark_runner
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	// UniformArrivalModel spaces the commands evenly, via the shared rate limiter
+	UniformArrivalModel = "uniform"
+	// PoissonArrivalModel draws the inter-arrival times from an exponential distribution ( open model )
+	PoissonArrivalModel = "poisson"
+)
+
+// poissonArrivals schedules the commands arrival times, shared across all workers, with exponentially
+// distributed inter-arrival times of mean 1/rate. The schedule does not depend on the replies: when the
+// workers fall behind it, the arrivals keep accumulating, exposing the queueing delay
+type poissonArrivals struct {
+	mutex sync.Mutex
+	rng   *rand.Rand
+	rate  float64
+	next  time.Time
+}
+
+func newPoissonArrivals(rate float64, seed int64) *poissonArrivals {
+	return &poissonArrivals{
+		rng:  rand.New(rand.NewSource(seed)),
+		rate: rate,
+	}
+}
+
+// nextArrival returns the scheduled arrival time of the next command
+func (a *poissonArrivals) nextArrival() time.Time {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.next.IsZero() {
+		a.next = time.Now()
+	}
+	a.next = a.next.Add(time.Duration(a.rng.ExpFloat64() / a.rate * float64(time.Second)))
+	return a.next
+}
+
+// NextArrival returns the scheduled arrival time of the next command, and true, when using the poisson
+// -arrival-model. The processors should wait until then to issue the command, and measure its latency
+// from the scheduled arrival, accounting for the time spent queued on the client
+func (l *BenchmarkRunner) NextArrival() (time.Time, bool) {
+	if l.arrivals == nil {
+		return time.Time{}, false
+	}
+	return l.arrivals.nextArrival(), true
+}
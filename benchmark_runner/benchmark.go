@@ -1,6 +1,9 @@
 package benchmark_runner
 
-import "bufio"
+import (
+	"bufio"
+	"time"
+)
 
 // Benchmark is an interface that represents the skeleton of a program
 // needed to run an insert or benchmark benchmark.
@@ -20,3 +23,87 @@ type Benchmark interface {
 	// GetConfigurationParametersMap returns the map of specific configurations used in the benchmark
 	GetConfigurationParametersMap() map[string]interface{}
 }
+
+// HealthChecker is a Benchmark that is also able to check whether the target database is still reachable
+type HealthChecker interface {
+	// Ping returns a non nil error if the target database did not reply within timeout
+	Ping(timeout time.Duration) error
+}
+
+// CommandIssuer is a Benchmark that is also able to issue standalone commands, outside of the benchmark workers.
+// It is used for the setup and teardown commands described on the json config file.
+type CommandIssuer interface {
+	// IssueCommand issues a single command with the provided arguments
+	IssueCommand(args []string) error
+}
+
+// TeardownProvider is a CommandIssuer with teardown commands of its own ( like dropping the index ), besides the ones
+// described on the json config file
+type TeardownProvider interface {
+	CommandIssuer
+	// GetTeardownCommands returns the commands to issue, and time, after the benchmark
+	GetTeardownCommands() [][]string
+}
+
+// Verifier is a Benchmark that is also able to check the replies of commands against the expected results
+// carried on the input rows ( correctness mode ).
+type Verifier interface {
+	// GetVerificationMap returns the verification tally ( checked commands and mismatches ). It returns an empty map if
+	// no verification took place
+	GetVerificationMap() map[string]interface{}
+}
+
+// CountVerifier is a Benchmark that is also able to check, once the benchmark is done, that every document it
+// loaded is accounted for on the target database
+type CountVerifier interface {
+	// StartCountVerification is called right before the benchmark starts, once the setup commands were issued
+	StartCountVerification() error
+	// GetCountVerificationMap returns the expected and actual number of loaded documents, and whether they match
+	// ( "Match" ). It returns an empty map if the check is disabled
+	GetCountVerificationMap() (map[string]interface{}, error)
+}
+
+// IndexMemoryReporter is a Benchmark that is also able to report, once the benchmark is done, the memory taken by
+// the index of the target database per indexed document, a key capacity planning figure
+type IndexMemoryReporter interface {
+	// GetIndexMemoryMap returns the total index memory ( "IndexMemoryBytes" ), the indexed documents ( "NumDocs" )
+	// and their ratio ( "BytesPerDoc" ). It returns an empty map if the report is disabled
+	GetIndexMemoryMap() (map[string]interface{}, error)
+}
+
+// ClusterDistributionReporter is a Benchmark that is also able to report how the commands were distributed
+// across the nodes of a cluster
+type ClusterDistributionReporter interface {
+	// GetClusterDistributionMap returns, per node, the issued commands ( "Commands" ) and transmitted bytes ( "TxBytes" ).
+	// It returns an empty map if not running against a cluster
+	GetClusterDistributionMap() map[string]interface{}
+}
+
+// CompressionEstimator is a Benchmark that is also able to estimate the compressibility of the transmitted commands
+type CompressionEstimator interface {
+	// GetCompressionMap returns the raw and compressed size of a sample of the transmitted commands, and their ratio
+	// ( "TxCompressionRatio" ). It returns an empty map if the estimation is disabled
+	GetCompressionMap() map[string]interface{}
+}
+
+// ReconnectsReporter is a Benchmark that is also able to report the connections re-established during the benchmark
+type ReconnectsReporter interface {
+	// GetReconnectsMap returns the number of re-established connections overall ( "TotalReconnects" ) and per
+	// worker ( "PerWorker" )
+	GetReconnectsMap() map[string]interface{}
+}
+
+// RunStateResetter is a Benchmark that keeps state across the runs of a -concurrency-sweep ( like tallies of the
+// issued commands ), which needs to be reset before each run
+type RunStateResetter interface {
+	// ResetRunState is called before each run but the first one
+	ResetRunState()
+}
+
+// ServerInfoCapturer is a Benchmark that is also able to snapshot the target database metrics ( like memory or
+// indexed documents ), enabling to report how they changed during the benchmark
+type ServerInfoCapturer interface {
+	// CaptureServerInfo returns the current value of the database metrics. It returns an empty map if the capture
+	// is disabled
+	CaptureServerInfo() (map[string]float64, error)
+}
@@ -6,11 +6,15 @@ import (
 	"flag"
 	"fmt"
 	"golang.org/x/time/rate"
+	"io"
 	"io/ioutil"
 	"log"
 	"math"
+	"math/rand"
 	"os"
+	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -23,9 +27,16 @@ import (
 
 const (
 	// defaultBatchSize - default size of batches to be inserted
-	defaultBatchSize           = 10000
-	defaultReadSize            = 4 << 20 // 4 MB
-	CurrentResultFormatVersion = "0.1"
+	defaultBatchSize = 10000
+	defaultReadSize  = 4 << 20 // 4 MB
+	// scanBatchSize - number of input rows on each batch sent to the workers
+	scanBatchSize = 100
+	// CurrentResultFormatVersion is the format of the results written to -json-out-file, bumped whenever their fields
+	// change
+	CurrentResultFormatVersion = "0.2"
+	// RunResultType and ConcurrencySweepResultType tell apart the single run and -concurrency-sweep results
+	RunResultType              = "run"
+	ConcurrencySweepResultType = "concurrency-sweep"
 
 	// WorkerPerQueue is the value for assigning each worker its own queue of batches
 	WorkerPerQueue = 0
@@ -38,20 +49,52 @@ const (
 // flags across all database systems and ultimately running a supplied Benchmark
 type BenchmarkRunner struct {
 	// flag fields
-	JsonOutFile     string
-	Metadata        string
-	batchSize       uint
-	workers         uint
-	maxRPS          uint64
-	limit           uint64
-	doLoad          bool
-	reportingPeriod time.Duration
-	fileName        string
-	start           time.Time
-	end             time.Time
+	JsonOutFile           string
+	JsonConfigFile        string
+	Metadata              string
+	batchSize             uint
+	workers               uint
+	maxInflightBatches    uint
+	maxRPS                uint64
+	maxTxBytes            uint64
+	maxRxBytes            uint64
+	maxErrorRate          float64
+	arrivalModel          string
+	arrivalSeed           int64
+	sampleRate            float64
+	seed                  int64
+	limit                 uint64
+	doLoad                bool
+	reportingPeriod       time.Duration
+	heartbeatInterval     time.Duration
+	heartbeatThreshold    time.Duration
+	fileName              string
+	expectedRows          uint64
+	shuffle               bool
+	shuffleWindow         uint64
+	shuffleSeed           int64
+	clientStats           bool
+	timeSeriesBuckets     bool
+	pinCPUs               bool
+	influxOutFile         string
+	hdrOutFile            string
+	reportSocketAddr      string
+	traceFile             string
+	concurrencySweep      string
+	influxTags            string
+	customLabelsStr       string
+	workerStagger         time.Duration
+	grafanaAnnotationURL  string
+	grafanaAnnotationTags string
+	grafanaAPIKey         string
+	// -rate-limit-<class> of each built-in command type
+	classMaxRPS map[string]*uint64
+	start       time.Time
+	end         time.Time
 
 	// non-flag fields
 	br                         *bufio.Reader
+	channels                   []*duplexChannel
 	detailedMapHistogramsMutex sync.RWMutex
 	detailedMapHistograms      map[string]*hdrhistogram.Histogram
 	setupWriteHistogram        *hdrhistogram.Histogram
@@ -61,6 +104,11 @@ type BenchmarkRunner struct {
 	perSecondHistograms      map[uint64]*hdrhistogram.Histogram
 	perSecondHistogramsMutex sync.RWMutex
 
+	// set by the heartbeat once the target database was unreachable for longer than -heartbeat-threshold
+	heartbeatExceeded int32
+	// set once the workers still in flight after the abort are no longer waited for, guarded by histogramsMutex
+	workersAbandoned bool
+
 	writeHistogram      *hdrhistogram.Histogram
 	inst_writeHistogram *hdrhistogram.Histogram
 
@@ -86,40 +134,151 @@ type BenchmarkRunner struct {
 	inst_totalHistogram *hdrhistogram.Histogram
 	totalTs             []DataPoint
 
+	// guards the class histograms and their instant ( per reporting period ) counterparts, so that each
+	// reporting period reads the counters and swaps the instant histograms as a consistent snapshot
+	histogramsMutex sync.Mutex
+
+	// number of commands per class, overall and on the current reporting period. Unlike the histograms
+	// counts, these account for every command, even if not sampled ( -sample-rate ) or above the highest trackable value
+	commandCounts     map[string]int64
+	instCommandCounts map[string]int64
+	// time to the first reply byte per class, guarded by histogramsMutex. Only populated when the processor
+	// measures it, enabling to distinguish the server compute time from the reply transfer time
+	firstByteHistograms map[string]*hdrhistogram.Histogram
+	// number of commands per query group, guarded by detailedMapHistogramsMutex
+	detailedCounts map[string]int64
+	// number of commands cut off by a server side query timeout per query group, guarded by detailedMapHistogramsMutex
+	queryTimeouts map[string]int64
+	// command types registered via -custom-labels, each with its own histogram guarded by histogramsMutex
+	customLabels     []string
+	customHistograms map[string]*hdrhistogram.Histogram
+	// number of commands per command type that is neither built-in nor custom, guarded by histogramsMutex
+	unrecognizedLabels map[string]int64
+
+	// done by each worker once its processor is initialized, so that the connections setup is not timed
+	initWg sync.WaitGroup
+	// offset from the benchmark start at which each worker was ready, when staggered via -worker-stagger
+	workerStarts []time.Duration
+
+	// closed to stop the reporting process, which reports the last ( partial ) period before finishing
+	reportDone chan struct{}
+	reportWg   sync.WaitGroup
+
+	clientStatsSampler clientStatsSampler
+	clientStatsTs      []DataPoint
+
+	influx       *influxWriter
+	reportSocket *reportSocket
+
+	// target database metrics captured before the benchmark
+	serverInfoBefore map[string]float64
+
+	// token buckets of the command types with a rate limit of their own, consulted by the processors
+	classLimiters map[string]*rate.Limiter
+
+	// commands arrival schedule, when using the poisson -arrival-model
+	arrivals *poissonArrivals
+
+	// rows read so far, when reading from STDIN
+	stdinRows *uint64
+
+	// input files opened by GetBufferedReader
+	inputFiles []*os.File
+
 	txTotalBytes uint64
 	rxTotalBytes uint64
 
+	// condition that stopped the benchmark before exhausting the input, if any
+	stopReason string
+
+	// error rate over the last -error-rate-window, updated on each reporting period. errorRateExceeded is set
+	// once it exceeds -max-error-rate, with errorRateTrigger holding the triggering window
+	errorRate         errorRateWindow
+	errorRateExceeded int32
+	errorRateTrigger  map[string]interface{}
+
+	// number of commands that replied with an error, and the subset of those that timed out
+	errorCount    uint64
+	timedOutCount uint64
+	// number of commands cut off by a server side query timeout ( partial or failed replies )
+	queryTimeoutCount uint64
+
+	// number of latencies per class above the histograms highest trackable value, that could not be recorded
+	histogramOverflows map[string]*uint64
+
+	// transmitted and received bytes per class
+	classBytes map[string]*classBytesCounter
+
+	// flags whose value should not be written to the results ( like passwords )
+	redactedFlags map[string]bool
+
+	// version of the benchmark tool, as set by the benchmark program
+	version string
+
+	// unique ID of the run and its -tag key=value pairs, recorded on the results
+	runID   string
+	runTags runTags
+
 	testResult TestResult
+
+	// results of each of the -concurrency-sweep levels
+	sweepResults []TestResult
+}
+
+// totalOps returns the number of issued commands, including the ones of custom and unrecognized command types, as
+// accounted on the overall latency histogram. It is the single source of the total on the results, summary and
+// reporting periods
+func (b *BenchmarkRunner) totalOps() int64 {
+	return b.commandCounts["allCommands"]
 }
 
 func (b *BenchmarkRunner) GetTotalsMap() map[string]interface{} {
 	configs := map[string]interface{}{}
 	//TotalOps
-	configs["TotalOps"] = b.totalHistogram.TotalCount()
+	configs["TotalOps"] = b.totalOps()
 
 	//SetupTotalWrites
-	configs["SetupWrites"] = b.setupWriteHistogram.TotalCount()
+	configs["SetupWrites"] = b.commandCounts["setupWrite"]
 
 	//TotalWrites
-	configs["Writes"] = b.writeHistogram.TotalCount()
+	configs["Writes"] = b.commandCounts["write"]
 
 	//TotalReads
-	configs["Reads"] = b.readHistogram.TotalCount()
+	configs["Reads"] = b.commandCounts["read"]
 
 	//TotalReadsCursor
-	configs["ReadsCursor"] = b.readCursorHistogram.TotalCount()
+	configs["ReadsCursor"] = b.commandCounts["readCursor"]
 
 	//TotalUpdates
-	configs["Updates"] = b.updateHistogram.TotalCount()
+	configs["Updates"] = b.commandCounts["update"]
 
 	//TotalDeletes
-	configs["Deletes"] = b.deleteHistogram.TotalCount()
+	configs["Deletes"] = b.commandCounts["delete"]
 
 	//TotalTxBytes
 	configs["TxBytes"] = b.txTotalBytes
 
 	//TotalRxBytes
 	configs["RxBytes"] = b.rxTotalBytes
+
+	//TotalErrors
+	configs["Errors"] = atomic.LoadUint64(&b.errorCount)
+
+	//TotalTimedOut
+	configs["TimedOut"] = atomic.LoadUint64(&b.timedOutCount)
+
+	//TotalQueryTimeouts
+	configs["QueryTimeouts"] = atomic.LoadUint64(&b.queryTimeoutCount)
+
+	//CustomLabels
+	if len(b.customLabels) > 0 {
+		configs["CustomLabels"] = b.GetCustomLabelsCountsMap()
+	}
+
+	//UnrecognizedLabels
+	if len(b.unrecognizedLabels) > 0 {
+		configs["UnrecognizedLabels"] = b.GetUnrecognizedLabelsMap()
+	}
 	//
 	//for k, _ := range b.detailedMapHistograms {
 	//	fmt.Println(k)
@@ -135,11 +294,11 @@ func (b *BenchmarkRunner) GetMeasuredRatiosMap() map[string]interface{} {
 	/////////
 	configs := map[string]interface{}{}
 
-	totalOps := b.totalHistogram.TotalCount()
-	writeRatio := float64(b.writeHistogram.TotalCount()+b.setupWriteHistogram.TotalCount()) / float64(totalOps)
-	readRatio := float64(b.readHistogram.TotalCount()+b.readCursorHistogram.TotalCount()) / float64(totalOps)
-	updateRatio := float64(b.updateHistogram.TotalCount()) / float64(totalOps)
-	deleteRatio := float64(b.deleteHistogram.TotalCount()) / float64(totalOps)
+	totalOps := b.totalOps()
+	writeRatio := float64(b.commandCounts["write"]+b.commandCounts["setupWrite"]) / float64(totalOps)
+	readRatio := float64(b.commandCounts["read"]+b.commandCounts["readCursor"]) / float64(totalOps)
+	updateRatio := float64(b.commandCounts["update"]) / float64(totalOps)
+	deleteRatio := float64(b.commandCounts["delete"]) / float64(totalOps)
 
 	//MeasuredWriteRatio
 	configs["MeasuredWriteRatio"] = writeRatio
@@ -163,16 +322,14 @@ func (l *BenchmarkRunner) GetOverallRatesMap() map[string]interface{} {
 	configs := map[string]interface{}{}
 
 	took := l.end.Sub(l.start)
-	writeCount := l.writeHistogram.TotalCount()
-	setupWriteCount := l.setupWriteHistogram.TotalCount()
-	totalWriteCount := writeCount + setupWriteCount
-	readCount := l.readHistogram.TotalCount()
-	readCursorCount := l.readCursorHistogram.TotalCount()
-	totalReadCount := readCount + readCursorCount
-	updateCount := l.updateHistogram.TotalCount()
-	deleteCount := l.deleteHistogram.TotalCount()
-
-	totalOps := totalWriteCount + totalReadCount + updateCount + deleteCount
+	writeCount := l.commandCounts["write"]
+	setupWriteCount := l.commandCounts["setupWrite"]
+	readCount := l.commandCounts["read"]
+	readCursorCount := l.commandCounts["readCursor"]
+	updateCount := l.commandCounts["update"]
+	deleteCount := l.commandCounts["delete"]
+
+	totalOps := l.totalOps()
 	txTotalBytes := atomic.LoadUint64(&l.txTotalBytes)
 	rxTotalBytes := atomic.LoadUint64(&l.rxTotalBytes)
 
@@ -194,12 +351,15 @@ func (l *BenchmarkRunner) GetOverallRatesMap() map[string]interface{} {
 	deleteRate := calculateRateMetrics(deleteCount, 0, took)
 	configs["deleteRate"] = deleteRate
 
+	for _, label := range l.customLabels {
+		configs[label+"Rate"] = calculateRateMetrics(l.commandCounts[label], 0, took)
+	}
+
 	overallOpsRate := calculateRateMetrics(totalOps, 0, took)
 	configs["overallOpsRate"] = overallOpsRate
 
-	for k, v := range l.detailedMapHistograms {
+	for k, count := range l.detailedCounts {
 		rateStr := k + "Rate"
-		count := v.TotalCount()
 		rate := calculateRateMetrics(count, 0, took)
 		configs[rateStr] = rate
 	}
@@ -218,6 +378,30 @@ func (l *BenchmarkRunner) GetOverallRatesMap() map[string]interface{} {
 	return configs
 }
 
+// GetQueueDepthMap returns the max and average number of batches waiting to be processed by the workers.
+// A large queue depth signals that the workers (and the DB) can't keep up with the offered load.
+func (b *BenchmarkRunner) GetQueueDepthMap() map[string]interface{} {
+	configs := map[string]interface{}{}
+	maxDepth := uint64(0)
+	totalDepth := uint64(0)
+	samples := uint64(0)
+	for _, c := range b.channels {
+		channelMax := atomic.LoadUint64(&c.maxQueueDepth)
+		if channelMax > maxDepth {
+			maxDepth = channelMax
+		}
+		totalDepth += atomic.LoadUint64(&c.totalQueueDepth)
+		samples += atomic.LoadUint64(&c.queueDepthSamples)
+	}
+	avgDepth := 0.0
+	if samples > 0 {
+		avgDepth = float64(totalDepth) / float64(samples)
+	}
+	configs["MaxQueueDepth"] = maxDepth
+	configs["AvgQueueDepth"] = avgDepth
+	return configs
+}
+
 func (b *BenchmarkRunner) GetTimeSeriesMap() map[string]interface{} {
 
 	configs := map[string]interface{}{}
@@ -234,6 +418,10 @@ func (b *BenchmarkRunner) GetTimeSeriesMap() map[string]interface{} {
 	configs["readCursorTs"] = b.readCursorTs
 	configs["updateTs"] = b.updateTs
 	configs["deleteTs"] = b.deleteTs
+	if b.clientStats {
+		sort.Sort(ByTimestamp(b.clientStatsTs))
+		configs["clientStatsTs"] = b.clientStatsTs
+	}
 
 	return configs
 }
@@ -247,30 +435,84 @@ func (b *BenchmarkRunner) GetPerSecondEncodedHistogramsMap() map[uint64]string {
 	return configs
 }
 
-var loader = &BenchmarkRunner{
-	setupWriteHistogram:      hdrhistogram.New(1, 1000000, 3),
-	inst_setupWriteHistogram: hdrhistogram.New(1, 1000000, 3),
-	setupWriteTs:             make([]DataPoint, 0, 10),
-	writeHistogram:           hdrhistogram.New(1, 1000000, 3),
-	inst_writeHistogram:      hdrhistogram.New(1, 1000000, 3),
-	writeTs:                  make([]DataPoint, 0, 10),
-	updateHistogram:          hdrhistogram.New(1, 1000000, 3),
-	inst_updateHistogram:     hdrhistogram.New(1, 1000000, 3),
-	updateTs:                 make([]DataPoint, 0, 10),
-	readHistogram:            hdrhistogram.New(1, 1000000, 3),
-	inst_readHistogram:       hdrhistogram.New(1, 1000000, 3),
-	readTs:                   make([]DataPoint, 0, 10),
-	readCursorHistogram:      hdrhistogram.New(1, 1000000, 3),
-	inst_readCursorHistogram: hdrhistogram.New(1, 1000000, 3),
-	readCursorTs:             make([]DataPoint, 0, 10),
-	deleteHistogram:          hdrhistogram.New(1, 1000000, 3),
-	inst_deleteHistogram:     hdrhistogram.New(1, 1000000, 3),
-	deleteTs:                 make([]DataPoint, 0, 10),
-	totalHistogram:           hdrhistogram.New(1, 1000000, 3),
-	inst_totalHistogram:      hdrhistogram.New(1, 1000000, 3),
-	totalTs:                  make([]DataPoint, 0, 10),
-	detailedMapHistograms:    make(map[string]*hdrhistogram.Histogram),
-	perSecondHistograms:      make(map[uint64]*hdrhistogram.Histogram),
+var loader = newBenchmarkRunner()
+
+func newBenchmarkRunner() *BenchmarkRunner {
+	l := &BenchmarkRunner{
+		redactedFlags: map[string]bool{},
+		runTags:       runTags{},
+	}
+	l.resetRunState()
+	return l
+}
+
+// resetRunState discards the histograms, counters and time series of a previous run, so that the workload can be
+// replayed ( -concurrency-sweep ) with each run being measured on its own
+func (l *BenchmarkRunner) resetRunState() {
+	l.setupWriteHistogram = hdrhistogram.New(1, 1000000, 3)
+	l.inst_setupWriteHistogram = hdrhistogram.New(1, 1000000, 3)
+	l.setupWriteTs = make([]DataPoint, 0, 10)
+	l.writeHistogram = hdrhistogram.New(1, 1000000, 3)
+	l.inst_writeHistogram = hdrhistogram.New(1, 1000000, 3)
+	l.writeTs = make([]DataPoint, 0, 10)
+	l.updateHistogram = hdrhistogram.New(1, 1000000, 3)
+	l.inst_updateHistogram = hdrhistogram.New(1, 1000000, 3)
+	l.updateTs = make([]DataPoint, 0, 10)
+	l.readHistogram = hdrhistogram.New(1, 1000000, 3)
+	l.inst_readHistogram = hdrhistogram.New(1, 1000000, 3)
+	l.readTs = make([]DataPoint, 0, 10)
+	l.readCursorHistogram = hdrhistogram.New(1, 1000000, 3)
+	l.inst_readCursorHistogram = hdrhistogram.New(1, 1000000, 3)
+	l.readCursorTs = make([]DataPoint, 0, 10)
+	l.deleteHistogram = hdrhistogram.New(1, 1000000, 3)
+	l.inst_deleteHistogram = hdrhistogram.New(1, 1000000, 3)
+	l.deleteTs = make([]DataPoint, 0, 10)
+	l.totalHistogram = hdrhistogram.New(1, 1000000, 3)
+	l.inst_totalHistogram = hdrhistogram.New(1, 1000000, 3)
+	l.totalTs = make([]DataPoint, 0, 10)
+	l.detailedMapHistograms = make(map[string]*hdrhistogram.Histogram)
+	l.detailedCounts = make(map[string]int64)
+	l.firstByteHistograms = make(map[string]*hdrhistogram.Histogram)
+	l.queryTimeouts = make(map[string]int64)
+	l.commandCounts = make(map[string]int64)
+	l.instCommandCounts = make(map[string]int64)
+	l.perSecondHistograms = make(map[uint64]*hdrhistogram.Histogram)
+	l.histogramOverflows = map[string]*uint64{
+		"setupWrite":  new(uint64),
+		"write":       new(uint64),
+		"update":      new(uint64),
+		"read":        new(uint64),
+		"readCursor":  new(uint64),
+		"delete":      new(uint64),
+		"allCommands": new(uint64),
+	}
+	l.classBytes = map[string]*classBytesCounter{
+		"setupWrite": {},
+		"write":      {},
+		"update":     {},
+		"read":       {},
+		"readCursor": {},
+		"delete":     {},
+	}
+	l.unrecognizedLabels = make(map[string]int64)
+	l.initCustomLabels()
+	l.clientStatsSampler = clientStatsSampler{}
+	l.clientStatsTs = nil
+	l.channels = nil
+	l.reportDone = nil
+	l.txTotalBytes = 0
+	l.rxTotalBytes = 0
+	l.stopReason = ""
+	l.errorRate.periods = nil
+	l.errorRateExceeded = 0
+	l.heartbeatExceeded = 0
+	l.workersAbandoned = false
+	l.errorRateTrigger = nil
+	l.workerStarts = nil
+	l.errorCount = 0
+	l.timedOutCount = 0
+	l.queryTimeoutCount = 0
+	l.testResult = TestResult{}
 }
 
 // GetBenchmarkRunner returns the singleton BenchmarkRunner for use in a benchmark program
@@ -284,22 +526,189 @@ func GetBenchmarkRunner() *BenchmarkRunner {
 func GetBenchmarkRunnerWithBatchSize(batchSize uint) *BenchmarkRunner {
 	// fill flag fields of BenchmarkRunner struct
 	flag.UintVar(&loader.workers, "workers", 8, "Number of parallel clients inserting")
+	flag.UintVar(&loader.maxInflightBatches, "max-inflight-batches", 0, "Maximum number of input batches (of 100 rows) read ahead of the workers, either queued or being issued, blocking the input scanning until the workers catch up. Bounds the memory of huge inputs against a slow server (0 = up to 3 batches per worker).")
 	flag.Uint64Var(&loader.limit, "requests", 0, "Number of total requests to issue (0 = all of the present in input file).")
 	flag.BoolVar(&loader.doLoad, "do-benchmark", true, "Whether to write databuild. Set this flag to false to check input read speed.")
 	flag.DurationVar(&loader.reportingPeriod, "reporting-period", 1*time.Second, "Period to report write stats")
-	flag.StringVar(&loader.fileName, "input", "", "File name to read databuild from")
+	flag.DurationVar(&loader.heartbeatInterval, "heartbeat-interval", 0, "Period to check that the target database is reachable (0 = disabled).")
+	flag.DurationVar(&loader.heartbeatThreshold, "heartbeat-threshold", 30*time.Second, "Abort the benchmark, printing the partial results, if the target database is unreachable for longer than this threshold. The commands still waiting on it are then waited for at most this threshold as well. Requires -heartbeat-interval.")
+	flag.StringVar(&loader.fileName, "input", "", "File name to read databuild from. Accepts a comma separated list of files and/or glob patterns (e.g. data.*), which are read in order as one continuous input.")
+	flag.Uint64Var(&loader.expectedRows, "expected-rows", 0, "Expected number of input rows when reading from STDIN (no -input), enabling to report the progress and the ETA of piped inputs (0 = unknown).")
+	flag.BoolVar(&loader.shuffle, "shuffle", false, "If set to true, the input rows are shuffled before being dispatched to the workers, breaking any locality present on the input file.")
+	flag.Uint64Var(&loader.shuffleWindow, "shuffle-window", 1000000, "Number of rows buffered in memory to shuffle the input (0 = buffer and shuffle the entire input). Requires -shuffle.")
+	flag.Int64Var(&loader.shuffleSeed, "shuffle-seed", 12345, "Random seed used to shuffle the input rows, for reproducibility. Requires -shuffle.")
+	flag.BoolVar(&loader.clientStats, "client-stats", false, "If set to true, the benchmark client heap, GC and goroutine stats are sampled on each reporting period and included on the time-series and json-out-file, helping to detect client side bottlenecks.")
+	flag.BoolVar(&loader.timeSeriesBuckets, "time-series-buckets", false, "If set to true, the time-series datapoints of each class on the json-out-file include the non empty latency histogram buckets (value -> count) of each reporting period, enabling to render latency heatmaps. Considerably increases the results size.")
+	flag.DurationVar(&loader.workerStagger, "worker-stagger", 0, "Delay between the start of each worker, so that the connections are not all established at once (a connection storm), smoothing the initial ramp. The benchmark starts once the first worker is ready, so the ramp-up is part of the measurement window, and the time each worker started is reported (0 = all workers start at once).")
+	flag.BoolVar(&loader.pinCPUs, "pin-cpus", false, "If set to true, each worker goroutine is locked to its own OS thread and, on Linux, that thread is pinned to a CPU (worker number modulo the number of CPUs), so that workers do not migrate across CPUs. Reduces the variance of the latency measurements on busy hosts.")
+	flag.StringVar(&loader.traceFile, "trace-file", "", "If set, the outcome of every command (timestamp_us, cmdType, cmdQueryId, latency_us, tx_bytes, rx_bytes, error) is written as an individual CSV record. Each worker writes to its own shard, named <trace-file>.<worker number>. If not set, no trace is written.")
+	flag.StringVar(&loader.concurrencySweep, "concurrency-sweep", "", "Comma separated list of worker counts (e.g. 1,2,4,8,16,32) to replay the whole input workload at, sequentially, measuring each level on its own. Overrides -workers. The -json-out-file holds the results of every level. Requires -input. If not set, the workload is issued once with -workers.")
+	flag.StringVar(&loader.hdrOutFile, "hdr-out-file", "", "Name of the file to write the final latency histogram of each command class to, using the HdrHistogram compressed binary encoding (values in microseconds), for post-processing with the HdrHistogram tooling. Each class is written to its own file, named after the class (e.g. results.hdr holds the read histogram on results.read.hdr and the overall one on results.allCommands.hdr). If not set, will not output the histograms.")
+	flag.StringVar(&loader.influxOutFile, "influx-out-file", "", "Name of the file (or named pipe) to write each reporting period metrics to, using the InfluxDB line protocol. If not set, will not output the line protocol.")
+	flag.StringVar(&loader.reportSocketAddr, "report-socket", "", "TCP [host]:port (e.g. :9999) or unix:<path> socket address to listen on, streaming the metrics of each reporting period as JSON lines to every connected subscriber (e.g. a live dashboard). Lines are dropped, rather than blocking the benchmark, while there are no subscribers or a subscriber falls behind. If not set, no socket is opened.")
+	flag.StringVar(&loader.customLabelsStr, "custom-labels", "", "Comma separated list of additional command types (the first column of the input rows, like SETUP_WRITE or READ), each accounted on its own latency histogram, counts and rates. The commands with a command type that is neither built-in nor custom are not accounted on any class, and reported as unrecognized.")
+	flag.StringVar(&loader.grafanaAnnotationURL, "grafana-annotation-url", "", "Grafana annotations API endpoint (e.g. http://grafana:3000/api/annotations) to push the benchmark run to, as a region annotation spanning from its start to its end, so that it shows up on the server side metrics dashboards. Failing to push it does not fail the benchmark. If not set, no annotation is pushed.")
+	flag.StringVar(&loader.grafanaAnnotationTags, "grafana-annotation-tags", "ftsb", "Comma separated list of tags of the -grafana-annotation-url annotation, used to filter the annotations shown on the dashboards.")
+	flag.StringVar(&loader.grafanaAPIKey, "grafana-api-key", "", "Grafana API key (or service account token) sent as a bearer token with the -grafana-annotation-url annotation.")
+	loader.RedactFlag("grafana-api-key")
+	flag.StringVar(&loader.influxTags, "influx-tags", "", "Comma separated list of key=value tags (e.g. index=idx1,env=ci) to add to the -influx-out-file lines, on top of the workers count tag.")
 	flag.Uint64Var(&loader.maxRPS, "max-rps", 0, "enable limiting the rate of queries per second, 0 = no limit. By default no limit is specified and the binaries will stress the DB up to the maximum. A normal \"modus operandi\" would be to initially stress the system ( no limit on RPS) and afterwards that we know the limit vary with lower rps configurations.")
+	loader.registerClassRateLimitFlags()
+	flag.Uint64Var(&loader.maxTxBytes, "max-tx-bytes", 0, "Stop issuing commands once this number of bytes was transmitted, summarizing the benchmark normally (0 = no limit). The commands in flight when the budget is reached are still accounted for, so it can be slightly exceeded.")
+	flag.Float64Var(&loader.maxErrorRate, "max-error-rate", 0, "Stop issuing commands once the percentage of commands replied with an error over the last -error-rate-window exceeds this value, summarizing the benchmark normally and reporting the triggering window (0 = no limit). Unlike a fixed total, tolerates bursts of recoverable errors while stopping on systemic failures. Requires a positive -reporting-period.")
+	flag.DurationVar(&loader.errorRate.window, "error-rate-window", 10*time.Second, "Length of the rolling window over which the error rate is checked against -max-error-rate. The error rate is only checked once the benchmark ran for a full window.")
+	flag.Uint64Var(&loader.maxRxBytes, "max-rx-bytes", 0, "Stop issuing commands once this number of bytes was received, summarizing the benchmark normally (0 = no limit). The commands in flight when the budget is reached are still accounted for, so it can be slightly exceeded.")
+	flag.StringVar(&loader.arrivalModel, "arrival-model", UniformArrivalModel, "Arrival model of the commands when limiting the rate with -max-rps. One of: uniform (evenly spaced commands), poisson (open model, with exponentially distributed inter-arrival times, and latencies measured from the scheduled arrival, including the time queued on the client).")
+	flag.Int64Var(&loader.arrivalSeed, "arrival-seed", 12345, "Random seed used to draw the inter-arrival times, for reproducibility. Requires -arrival-model poisson.")
+	flag.Float64Var(&loader.sampleRate, "sample-rate", 1.0, "Fraction of the commands, randomly picked, whose latency is recorded on the histograms (0 < rate <= 1). Every command is still accounted for on the throughput. Lowers the client overhead at very high throughputs, at the cost of a lower confidence on the tail latency percentiles.")
+	flag.Int64Var(&loader.seed, "seed", 0, "Random seed of the benchmark randomness (like the -sample-rate sampling or the DB specific -pipeline-jitter), for reproducibility. The effective seed is printed at startup and recorded on the json-out-file (0 = a random seed).")
 	flag.StringVar(&loader.JsonOutFile, "json-out-file", "", "Name of json output file to output benchmark results. If not set, will not print to json.")
+	flag.StringVar(&loader.JsonConfigFile, "json-config-file", "", "Name of the json benchmark suite specification file (produced alongside the input files) describing the setup and teardown commands to issue before and after the benchmark. If not set, no setup or teardown commands are issued.")
 	flag.StringVar(&loader.Metadata, "metadata-string", "", "Metadata string to add to json-out-file. If -json-out-file is not set, will not use this option.")
+	flag.Var(loader.runTags, "tag", "key=value tag (e.g. -tag env=ci -tag branch=master) to add to the json-out-file, alongside the unique run ID generated on each invocation, enabling to index and query the results of past runs. Can be repeated, or set to a comma separated list of key=value pairs.")
 	return loader
 }
 
 // RunBenchmark takes in a Benchmark b, a bufio.Reader br, and holders for number of metrics and rows
 // and reads those to run the benchmark benchmark
 func (l *BenchmarkRunner) RunBenchmark(b Benchmark, workQueues uint) {
+	l.seedRandom()
+	if l.expectedRows > 0 && l.fileName != "" {
+		log.Fatalf("-expected-rows only applies when reading from STDIN, and -input was set to %s", l.fileName)
+	}
 	l.br = l.GetBufferedReader()
 
+	if l.customLabelsStr != "" {
+		var err error
+		if l.customLabels, err = parseCustomLabels(l.customLabelsStr); err != nil {
+			log.Fatal(err)
+		}
+		l.initCustomLabels()
+	}
+
+	if err := l.parseErrorRateFlags(); err != nil {
+		log.Fatal(err)
+	}
+
+	if l.workerStagger < 0 {
+		log.Fatalf("-worker-stagger can't be negative")
+	}
+
+	if l.sampleRate <= 0.0 || l.sampleRate > 1.0 {
+		log.Fatalf("-sample-rate must be within ]0,1]. Got %f", l.sampleRate)
+	}
+
+	var sweepLevels []uint
+	if l.concurrencySweep != "" {
+		var err error
+		if sweepLevels, err = l.parseConcurrencySweep(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	switch l.arrivalModel {
+	case UniformArrivalModel:
+	case PoissonArrivalModel:
+		if l.maxRPS == 0 {
+			log.Fatalf("-arrival-model %s requires a target rate, via -max-rps", l.arrivalModel)
+		}
+		for _, f := range classRateLimitFlags {
+			if *l.classMaxRPS[f.cmdType] > 0 {
+				log.Fatalf("-%s can't be used with -arrival-model %s, given the arrivals are scheduled at the -max-rps rate for every command type", f.name, l.arrivalModel)
+			}
+		}
+		l.arrivals = newPoissonArrivals(float64(l.maxRPS), l.arrivalSeed)
+	default:
+		log.Fatalf("unknown -arrival-model %s. Expected one of: %s, %s", l.arrivalModel, UniformArrivalModel, PoissonArrivalModel)
+	}
+
+	var config BenchmarkConfig
+	issuer, canIssue := b.(CommandIssuer)
+	if l.JsonConfigFile != "" {
+		var err error
+		if sameFile(l.JsonConfigFile, l.JsonOutFile) {
+			log.Fatalf("-json-config-file and -json-out-file point to the same file (%s). The results would overwrite the config file", l.JsonConfigFile)
+		}
+		if !canIssue {
+			log.Fatalf("the benchmark does not support issuing the setup and teardown commands of %s", l.JsonConfigFile)
+		}
+		config, err = ReadBenchmarkConfig(l.JsonConfigFile)
+		if err != nil {
+			log.Fatalf("cannot read json config file %s: %v", l.JsonConfigFile, err)
+		}
+		issueStageCommands(issuer, "setup", config.Setup.args(), true)
+	}
+
+	if l.reportSocketAddr != "" {
+		var err error
+		l.reportSocket, err = newReportSocket(l.reportSocketAddr)
+		if err != nil {
+			log.Fatalf("cannot listen on report socket %s: %v", l.reportSocketAddr, err)
+		}
+	}
+	if l.concurrencySweep != "" {
+		l.runConcurrencySweep(b, workQueues, sweepLevels)
+	} else {
+		l.runWorkload(b, workQueues)
+		l.collectResults(b)
+	}
+	if l.reportSocket != nil {
+		if dropped := l.reportSocket.close(); dropped > 0 {
+			fmt.Printf("\tReport socket: %d lines dropped given no subscribers or slow subscribers\n", dropped)
+		}
+	}
+
+	// the teardown is issued before the summary ( but after capturing the results ), so that its timings are reported
+	var teardown [][]string
+	if l.JsonConfigFile != "" {
+		teardown = append(teardown, config.Teardown.args()...)
+	}
+	if provider, ok := b.(TeardownProvider); ok {
+		issuer = provider
+		teardown = append(teardown, provider.GetTeardownCommands()...)
+	}
+	teardownTimings := []map[string]interface{}{}
+	// the teardown is skipped when the target database is unreachable
+	if len(teardown) > 0 && !l.HeartbeatAborted() {
+		teardownTimings = issueStageCommands(issuer, "teardown", teardown, false)
+	}
+	if l.concurrencySweep != "" {
+		l.sweepSummary(teardownTimings)
+	} else {
+		l.testResult.Teardown = teardownTimings
+		l.summary()
+	}
+	if l.HeartbeatAborted() {
+		log.Fatalf("the benchmark was aborted given the target database was unreachable for longer than -heartbeat-threshold %v", l.heartbeatThreshold)
+	}
+	if l.concurrencySweep != "" {
+		return
+	}
+	if match, ok := l.testResult.CountVerification["Match"].(bool); ok && !match {
+		log.Fatalf("the loaded documents count verification failed: %v", l.testResult.CountVerification)
+	}
+}
+
+// runWorkload issues the whole input workload with the configured number of workers, returning once every
+// worker is done
+func (l *BenchmarkRunner) runWorkload(b Benchmark, workQueues uint) {
+	l.serverInfoBefore = captureServerInfo(b)
+	if verifier, ok := b.(CountVerifier); ok {
+		if err := verifier.StartCountVerification(); err != nil {
+			log.Fatalf("cannot start the count verification: %v", err)
+		}
+	}
+
+	if l.influxOutFile != "" {
+		var err error
+		l.influx, err = newInfluxWriter(l.influxOutFile, l.influxTags, l.workers)
+		if err != nil {
+			log.Fatalf("cannot create influx out file %s: %v", l.influxOutFile, err)
+		}
+	}
+
 	channels := l.createChannels(workQueues)
+	l.channels = channels
 	// Launch all worker processes in background
 
 	var requestRate = Inf
@@ -309,62 +718,253 @@ func (l *BenchmarkRunner) RunBenchmark(b Benchmark, workQueues uint) {
 		requestBurst = int(l.workers) //int(b.workers)
 	}
 	var rateLimiter = rate.NewLimiter(requestRate, requestBurst)
+	l.classLimiters = l.newClassRateLimiters()
 
 	var wg sync.WaitGroup
-	for i := 0; i < int(l.workers); i++ {
-		wg.Add(1)
-		go l.work(b, &wg, channels[i%len(channels)], i, rateLimiter, l.maxRPS != 0)
+	wg.Add(int(l.workers))
+	launch := func(workerNum int) {
+		go l.work(b, &wg, channels[workerNum%len(channels)], workerNum, rateLimiter, l.maxRPS != 0 && l.arrivals == nil)
+	}
+	if l.workerStagger > 0 {
+		// the benchmark starts once the first worker is ready, the remaining ones joining along the way
+		l.initWg.Add(1)
+		launch(0)
+	} else {
+		l.initWg.Add(int(l.workers))
+		for i := 0; i < int(l.workers); i++ {
+			launch(i)
+		}
+	}
+
+	heartbeatDone := make(chan struct{})
+	aborted := make(chan struct{})
+	if hc, ok := b.(HealthChecker); ok && l.heartbeatInterval > 0 {
+		go l.heartbeat(hc, l.heartbeatInterval, l.heartbeatThreshold, heartbeatDone, aborted)
 	}
 
 	w := new(tabwriter.Writer)
 	w.Init(os.Stderr, 20, 0, 0, ' ', tabwriter.AlignRight)
 	// Start scan process - actual databuild read process
+	l.initWg.Wait()
 	l.start = time.Now()
+	scanDone := make(chan struct{})
+	staggerDone := scanDone
+	if l.workerStagger > 0 {
+		staggerDone = l.staggerWorkers(launch, wg.Done, scanDone)
+	}
+	snapshotDone := make(chan struct{})
+	l.handleSnapshotSignal(snapshotDone)
 
-	l.scan(b, channels, l.start, w)
+	workersDone := make(chan struct{})
+	go func() {
+		l.scan(b, channels, l.start, w)
+		close(scanDone)
 
-	// After scan process completed (no more databuild to come) - begin shutdown process
+		// After scan process completed (no more databuild to come) - begin shutdown process
 
-	// Close all communication channels to/from workers
-	for _, c := range channels {
-		c.close()
-	}
+		// Close all communication channels to/from workers
+		for _, c := range channels {
+			c.close()
+		}
 
-	// Wait for all workers to finish
-	wg.Wait()
+		// Wait for all workers to finish
+		<-staggerDone
+		wg.Wait()
+		close(workersDone)
+	}()
+	l.waitForWorkers(workersDone, aborted)
+	close(heartbeatDone)
+	if l.HeartbeatAborted() {
+		l.stopReason = "heartbeat-threshold"
+	}
+	close(snapshotDone)
 	l.end = time.Now()
+	if l.reportDone != nil {
+		close(l.reportDone)
+		l.reportWg.Wait()
+	}
+	if l.influx != nil {
+		if err := l.influx.close(); err != nil {
+			log.Printf("error while closing influx out file %s: %v\n", l.influxOutFile, err)
+		}
+	}
+}
+
+// collectResults fills the test result with the metrics collected up until now
+func (l *BenchmarkRunner) collectResults(b Benchmark) {
 	l.testResult.DBSpecificConfigs = b.GetConfigurationParametersMap()
+	l.testResult.RunConfig = l.GetRunConfigMap()
 	l.testResult.Totals = l.GetTotalsMap()
 	l.testResult.MeasuredRatios = l.GetMeasuredRatiosMap()
 	l.testResult.OverallRates = l.GetOverallRatesMap()
 	l.testResult.TimeSeries = l.GetTimeSeriesMap()
+	l.testResult.ThroughputStability = l.GetThroughputStabilityMap()
+	l.testResult.QueueDepth = l.GetQueueDepthMap()
+	l.testResult.WorkerStagger = l.GetWorkerStaggerMap()
+	l.testResult.ClientStats = l.GetClientStatsMap()
+	l.testResult.Verification = map[string]interface{}{}
+	if verifier, ok := b.(Verifier); ok {
+		l.testResult.Verification = verifier.GetVerificationMap()
+	}
+	l.testResult.CountVerification = map[string]interface{}{}
+	if verifier, ok := b.(CountVerifier); ok {
+		var err error
+		if l.testResult.CountVerification, err = verifier.GetCountVerificationMap(); err != nil {
+			log.Printf("cannot verify the loaded documents count: %v\n", err)
+			l.testResult.CountVerification = map[string]interface{}{"Match": false, "Error": err.Error()}
+		}
+	}
+	l.testResult.ServerInfo = getServerInfoMap(l.serverInfoBefore, captureServerInfo(b))
+	l.testResult.IndexMemory = map[string]interface{}{}
+	if reporter, ok := b.(IndexMemoryReporter); ok {
+		var err error
+		if l.testResult.IndexMemory, err = reporter.GetIndexMemoryMap(); err != nil {
+			log.Printf("cannot report the index memory per document: %v\n", err)
+			l.testResult.IndexMemory = map[string]interface{}{}
+		}
+	}
+	l.testResult.ClusterDistribution = map[string]interface{}{}
+	if reporter, ok := b.(ClusterDistributionReporter); ok {
+		l.testResult.ClusterDistribution = reporter.GetClusterDistributionMap()
+	}
+	l.testResult.Compression = map[string]interface{}{}
+	if estimator, ok := b.(CompressionEstimator); ok {
+		l.testResult.Compression = estimator.GetCompressionMap()
+	}
+	l.testResult.Reconnects = map[string]interface{}{}
+	if reporter, ok := b.(ReconnectsReporter); ok {
+		l.testResult.Reconnects = reporter.GetReconnectsMap()
+	}
+	l.testResult.HistogramOverflows = l.GetHistogramOverflowsMap()
+	l.testResult.ErrorRateStop = map[string]interface{}{}
+	if l.errorRateTrigger != nil {
+		l.testResult.ErrorRateStop = l.errorRateTrigger
+	}
+	l.testResult.QueryTimeouts = l.GetQueryTimeoutsMap()
+	l.testResult.ClassBytes = l.GetClassBytesMap()
 	l.testResult.OverallQuantiles = l.GetOverallQuantiles()
+	l.testResult.OverallFirstByteQuantiles = l.GetOverallFirstByteQuantiles()
 	l.testResult.PerSecondEncodedHistograms = l.GetPerSecondEncodedHistogramsMap()
 	l.testResult.Limit = l.limit
 	l.testResult.Workers = l.workers
 	l.testResult.MaxRps = l.maxRPS
-	l.summary()
+	l.testResult.Seed = l.seed
+	l.testResult.Version = l.version
+	l.testResult.RunID = l.runID
+	l.testResult.Tags = l.runTags
+}
+
+// SetVersion sets the version of the benchmark tool ( like its git sha1 ), to be recorded on the results
+func (l *BenchmarkRunner) SetVersion(version string) {
+	l.version = version
+}
+
+// seedRandom resolves the effective -seed, seeding the shared random generator with it, and prints it alongside
+// the version and the resolved value of every flag, so that the run can be exactly reproduced later
+func (l *BenchmarkRunner) seedRandom() {
+	if l.runID == "" {
+		var err error
+		if l.runID, err = newRunID(); err != nil {
+			log.Fatalf("cannot generate the run ID: %v", err)
+		}
+	}
+	if l.seed == 0 {
+		l.seed = time.Now().UnixNano()
+		// the run config records the effective seed, instead of the random seed placeholder
+		if err := flag.Set("seed", strconv.FormatInt(l.seed, 10)); err != nil {
+			log.Fatalf("cannot set the effective -seed: %v", err)
+		}
+	}
+	rand.Seed(l.seed)
+	version := l.version
+	if version == "" {
+		version = "unknown"
+	}
+	log.Printf("Run ID %s, using random seed %d (version %s)\n", l.runID, l.seed, version)
+	configs := l.GetRunConfigMap()
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		// only the actual flags, so that the resolved configuration can be passed back as is
+		if flag.Lookup(name) != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	resolved := make([]string, 0, len(names))
+	for _, name := range names {
+		resolved = append(resolved, fmt.Sprintf("-%s=%v", name, configs[name]))
+	}
+	log.Printf("Resolved configuration: %s\n", strings.Join(resolved, " "))
+}
+
+// RedactFlag marks a flag whose value should not be written to the run config of the results ( like passwords )
+func (l *BenchmarkRunner) RedactFlag(name string) {
+	l.redactedFlags[name] = true
+}
+
+// GetRunConfigMap returns the effective value of every flag ( including the DB specific ones ), so that
+// a run can be reproduced from its result file alone
+func (l *BenchmarkRunner) GetRunConfigMap() map[string]interface{} {
+	configs := map[string]interface{}{}
+	flag.VisitAll(func(f *flag.Flag) {
+		value := f.Value.String()
+		if l.redactedFlags[f.Name] && value != "" {
+			value = "<redacted>"
+		}
+		configs[f.Name] = value
+	})
+	configs["batch-size"] = scanBatchSize
+	return configs
 }
 
 // GetBufferedReader returns the buffered Reader that should be used by the loader
 func (l *BenchmarkRunner) GetBufferedReader() *bufio.Reader {
 	if l.br == nil {
+		var r io.Reader
 		if len(l.fileName) > 0 {
-			// Read from specified file
-			file, err := os.Open(l.fileName)
+			// Read from the specified file(s), concatenated in order
+			fileNames, err := expandInputFileNames(l.fileName)
 			if err != nil {
-				log.Fatalf("cannot open file for read %s: %v", l.fileName, err)
+				log.Fatalf("cannot expand input file names %s: %v", l.fileName, err)
 				return nil
 			}
-			l.br = bufio.NewReaderSize(file, defaultReadSize)
+			readers := make([]io.Reader, 0, len(fileNames))
+			for _, fileName := range fileNames {
+				file, err := os.Open(fileName)
+				if err != nil {
+					log.Fatalf("cannot open file for read %s: %v", fileName, err)
+					return nil
+				}
+				readers = append(readers, file)
+				l.inputFiles = append(l.inputFiles, file)
+			}
+			r = concatenateReaders(readers)
 		} else {
-			// Read from STDIN
-			l.br = bufio.NewReaderSize(os.Stdin, defaultReadSize)
+			// Read from STDIN, counting the rows given there is no known total to report the progress against
+			l.stdinRows = new(uint64)
+			r = &rowCountingReader{r: os.Stdin, rows: l.stdinRows}
 		}
+		if l.shuffle {
+			log.Printf("Shuffling input rows with a window of %d rows and seed %d\n", l.shuffleWindow, l.shuffleSeed)
+			r = newShuffleReader(r, l.shuffleWindow, l.shuffleSeed)
+		}
+		l.br = bufio.NewReaderSize(r, defaultReadSize)
 	}
 	return l.br
 }
 
+// closeInput closes the input files, so that GetBufferedReader reopens them from the start
+func (l *BenchmarkRunner) closeInput() (err error) {
+	for _, file := range l.inputFiles {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	l.inputFiles = nil
+	l.br = nil
+	return
+}
+
 // createChannels create channels from which workers would receive tasks
 // Number of workers may be different from number of channels, thus we may have
 // multiple workers per channel
@@ -397,32 +997,85 @@ func (l *BenchmarkRunner) scan(b Benchmark, channels []*duplexChannel, start tim
 	// Start background reporting process
 	// TODO why it is here? May be it could be moved one level up?
 	if l.reportingPeriod.Nanoseconds() > 0 {
+		l.reportDone = make(chan struct{})
+		l.reportWg.Add(1)
 		go l.report(l.reportingPeriod, start, w)
 	}
 
 	// Scan incoming databuild
-	return scanWithIndexer(channels, 100, l.limit, l.br, b.GetCmdDecoder(l.br), b.GetBatchFactory(), b.GetCommandIndexer(uint(len(channels))))
+	return scanWithIndexer(channels, scanBatchSize, l.limit, int(l.maxInflightBatches), l.br, b.GetCmdDecoder(l.br), b.GetBatchFactory(), b.GetCommandIndexer(uint(len(channels))), l.stopScanning)
 }
 
 // work is the processing function for each worker in the loader
 func (l *BenchmarkRunner) work(b Benchmark, wg *sync.WaitGroup, c *duplexChannel, workerNum int, rateLimiter *rate.Limiter, useRateLimiter bool) {
 
+	if l.pinCPUs {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		cpu := workerNum % runtime.NumCPU()
+		if err := pinThreadToCPU(cpu); err != nil {
+			log.Printf("worker %d could not be pinned to CPU %d: %v\n", workerNum, cpu, err)
+		}
+	}
+
 	// Prepare processor
 	proc := b.GetProcessor()
 	proc.Init(workerNum, l.doLoad, int(l.workers))
+	l.initWg.Done()
+	sampler := rand.New(rand.NewSource(l.seed + int64(workerNum)))
+	var trace *traceWriter
+	if l.traceFile != "" {
+		var err error
+		trace, err = newTraceWriter(l.traceFile, workerNum)
+		if err != nil {
+			log.Fatalf("cannot create trace file %s: %v", traceShardName(l.traceFile, workerNum), err)
+		}
+	}
 
-	// Process batches coming from duplexChannel.toWorker queue
-	// and send ACKs into duplexChannel.toScanner queue
-	for b := range c.toWorker {
-		stats := proc.ProcessBatch(b, l.doLoad, rateLimiter, useRateLimiter)
+	// recordStats accounts for the commands of a processed batch
+	recordStats := func(stats Stat) {
 		cmdStats := stats.CmdStats()
+		if trace != nil {
+			for pos := range cmdStats {
+				if err := trace.write(cmdStats[pos]); err != nil {
+					// stop tracing, without affecting the benchmark
+					log.Printf("error while writing to trace file %s: %v. Tracing stopped for worker %d\n", traceShardName(l.traceFile, workerNum), err, workerNum)
+					trace.close()
+					trace = nil
+					break
+				}
+			}
+		}
+		l.histogramsMutex.Lock()
+		if l.workersAbandoned {
+			l.histogramsMutex.Unlock()
+			return
+		}
 		for pos := 0; pos < len(cmdStats); pos++ {
 			cmdStat := cmdStats[pos]
-			_ = l.totalHistogram.RecordValue(int64(cmdStat.Latency()))
-			_ = l.inst_totalHistogram.RecordValue(int64(cmdStat.Latency()))
+			// every command is counted, but only the sampled ones are recorded on the histograms
+			sampled := l.sampleRate >= 1.0 || sampler.Float64() < l.sampleRate
+			l.countCommand("allCommands")
+			if sampled {
+				l.countOverflow("allCommands", l.totalHistogram.RecordValue(int64(cmdStat.Latency())))
+				_ = l.inst_totalHistogram.RecordValue(int64(cmdStat.Latency()))
+				if cmdStat.FirstByteLatency() > 0 {
+					l.recordFirstByte("allCommands", cmdStat)
+					l.recordFirstByte(labelClasses[string(cmdStat.Label())], cmdStat)
+				}
+			}
 
 			atomic.AddUint64(&l.txTotalBytes, cmdStat.Tx())
 			atomic.AddUint64(&l.rxTotalBytes, cmdStat.Rx())
+			if cmdStat.IsError() {
+				atomic.AddUint64(&l.errorCount, 1)
+			}
+			if cmdStat.IsTimedOut() {
+				atomic.AddUint64(&l.timedOutCount, 1)
+			}
+			if cmdStat.IsQueryTimedOut() {
+				atomic.AddUint64(&l.queryTimeoutCount, 1)
+			}
 			labelStr := string(cmdStat.Label())
 			querystr := string(cmdStat.CmdQueryId())
 			groupAndQuery := labelStr + "-" + querystr
@@ -430,75 +1083,257 @@ func (l *BenchmarkRunner) work(b Benchmark, wg *sync.WaitGroup, c *duplexChannel
 			if _, exist := l.detailedMapHistograms[groupAndQuery]; !exist {
 				l.detailedMapHistograms[groupAndQuery] = hdrhistogram.New(1, 1000000, 3)
 			}
-			l.detailedMapHistograms[groupAndQuery].RecordValue(int64(cmdStat.Latency()))
+			l.detailedCounts[groupAndQuery]++
+			if cmdStat.IsQueryTimedOut() {
+				l.queryTimeouts[groupAndQuery]++
+			}
+			if sampled {
+				l.detailedMapHistograms[groupAndQuery].RecordValue(int64(cmdStat.Latency()))
+			}
 			l.detailedMapHistogramsMutex.Unlock()
 
-			ts := cmdStat.StartTs()
-			l.perSecondHistogramsMutex.Lock()
-			if _, exist := l.perSecondHistograms[ts]; !exist {
-				l.perSecondHistograms[ts] = hdrhistogram.New(1, 1000000, 3)
+			if sampled {
+				ts := cmdStat.StartTs()
+				l.perSecondHistogramsMutex.Lock()
+				if _, exist := l.perSecondHistograms[ts]; !exist {
+					l.perSecondHistograms[ts] = hdrhistogram.New(1, 1000000, 3)
+				}
+				l.perSecondHistograms[ts].RecordValue(int64(cmdStat.Latency()))
+				l.perSecondHistogramsMutex.Unlock()
 			}
-			l.perSecondHistograms[ts].RecordValue(int64(cmdStat.Latency()))
-			l.perSecondHistogramsMutex.Unlock()
 
 			switch labelStr {
 			case "SETUP_WRITE":
-				_ = l.setupWriteHistogram.RecordValue(int64(cmdStat.Latency()))
-				_ = l.inst_setupWriteHistogram.RecordValue(int64(cmdStat.Latency()))
+				l.countBytes("setupWrite", cmdStat)
+				l.countCommand("setupWrite")
+				if sampled {
+					l.countOverflow("setupWrite", l.setupWriteHistogram.RecordValue(int64(cmdStat.Latency())))
+					_ = l.inst_setupWriteHistogram.RecordValue(int64(cmdStat.Latency()))
+				}
 
 				break
 			case "WRITE":
-				_ = l.writeHistogram.RecordValue(int64(cmdStat.Latency()))
-				_ = l.inst_writeHistogram.RecordValue(int64(cmdStat.Latency()))
+				l.countBytes("write", cmdStat)
+				l.countCommand("write")
+				if sampled {
+					l.countOverflow("write", l.writeHistogram.RecordValue(int64(cmdStat.Latency())))
+					_ = l.inst_writeHistogram.RecordValue(int64(cmdStat.Latency()))
+				}
 
 				break
 			case "UPDATE":
-				_ = l.updateHistogram.RecordValue(int64(cmdStat.Latency()))
-				_ = l.inst_updateHistogram.RecordValue(int64(cmdStat.Latency()))
+				l.countBytes("update", cmdStat)
+				l.countCommand("update")
+				if sampled {
+					l.countOverflow("update", l.updateHistogram.RecordValue(int64(cmdStat.Latency())))
+					_ = l.inst_updateHistogram.RecordValue(int64(cmdStat.Latency()))
+				}
 
 				break
 			case "READ":
-				_ = l.readHistogram.RecordValue(int64(cmdStat.Latency()))
-				_ = l.inst_readHistogram.RecordValue(int64(cmdStat.Latency()))
+				l.countBytes("read", cmdStat)
+				l.countCommand("read")
+				if sampled {
+					l.countOverflow("read", l.readHistogram.RecordValue(int64(cmdStat.Latency())))
+					_ = l.inst_readHistogram.RecordValue(int64(cmdStat.Latency()))
+				}
 
 				break
 			case "CURSOR_READ":
-				_ = l.readCursorHistogram.RecordValue(int64(cmdStat.Latency()))
-				_ = l.inst_readCursorHistogram.RecordValue(int64(cmdStat.Latency()))
+				l.countBytes("readCursor", cmdStat)
+				l.countCommand("readCursor")
+				if sampled {
+					l.countOverflow("readCursor", l.readCursorHistogram.RecordValue(int64(cmdStat.Latency())))
+					_ = l.inst_readCursorHistogram.RecordValue(int64(cmdStat.Latency()))
+				}
 
 				break
 			case "DELETE":
-				_ = l.deleteHistogram.RecordValue(int64(cmdStat.Latency()))
-				_ = l.inst_deleteHistogram.RecordValue(int64(cmdStat.Latency()))
+				l.countBytes("delete", cmdStat)
+				l.countCommand("delete")
+				if sampled {
+					l.countOverflow("delete", l.deleteHistogram.RecordValue(int64(cmdStat.Latency())))
+					_ = l.inst_deleteHistogram.RecordValue(int64(cmdStat.Latency()))
+				}
 
 				break
+			default:
+				l.recordCustomLabel(labelStr, cmdStat, sampled)
 			}
 		}
+		l.histogramsMutex.Unlock()
+	}
+
+	// Process batches coming from duplexChannel.toWorker queue
+	// and send ACKs into duplexChannel.toScanner queue
+	for b := range c.toWorker {
+		if l.HeartbeatAborted() || l.exceededTransferBudget() != "" {
+			// the batches already dispatched when the benchmark is aborted or the transfer budget is reached are not
+			// issued
+			c.sendToScanner()
+			continue
+		}
+		recordStats(proc.ProcessBatch(b, l.doLoad, rateLimiter, useRateLimiter))
 		c.sendToScanner()
 	}
 
+	// issue the commands still held by the processor across batches
+	if flusher, ok := proc.(ProcessorFlusher); ok {
+		recordStats(flusher.Flush(l.doLoad))
+	}
+
 	// Close proc if necessary
 	switch c := proc.(type) {
 	case ProcessorCloser:
 		c.Close(l.doLoad)
 	}
+	if trace != nil {
+		if err := trace.close(); err != nil {
+			log.Printf("error while closing trace file %s: %v\n", traceShardName(l.traceFile, workerNum), err)
+		}
+	}
 
 	wg.Done()
 }
 
+// exceededTransferBudget returns the flag of the transfer budget ( -max-tx-bytes or -max-rx-bytes ) already reached,
+// or an empty string if none was
+func (l *BenchmarkRunner) exceededTransferBudget() string {
+	if l.maxTxBytes > 0 && atomic.LoadUint64(&l.txTotalBytes) >= l.maxTxBytes {
+		return "max-tx-bytes"
+	}
+	if l.maxRxBytes > 0 && atomic.LoadUint64(&l.rxTotalBytes) >= l.maxRxBytes {
+		return "max-rx-bytes"
+	}
+	return ""
+}
+
+// stopScanning is checked by the scanner before reading each item, recording the transfer budget or error rate that
+// stopped the benchmark, if any. The unreachable target database is recorded once the workers are done, given the
+// scanner may then be left waiting on them
+func (l *BenchmarkRunner) stopScanning() bool {
+	if l.HeartbeatAborted() {
+		return true
+	}
+	if atomic.LoadInt32(&l.errorRateExceeded) == 1 {
+		l.stopReason = "max-error-rate"
+		return true
+	}
+	if budget := l.exceededTransferBudget(); budget != "" {
+		l.stopReason = budget
+		return true
+	}
+	return false
+}
+
+// countOverflow accounts for latencies that could not be recorded on the class histogram given they are
+// above its highest trackable value
+func (l *BenchmarkRunner) countOverflow(class string, recordErr error) {
+	if recordErr != nil {
+		atomic.AddUint64(l.histogramOverflows[class], 1)
+	}
+}
+
+// labelClasses maps the input command types to their class
+var labelClasses = map[string]string{
+	"SETUP_WRITE": "setupWrite",
+	"WRITE":       "write",
+	"UPDATE":      "update",
+	"READ":        "read",
+	"CURSOR_READ": "readCursor",
+	"DELETE":      "delete",
+}
+
+// recordFirstByte records the time to the first reply byte of a command on its class histogram.
+// Must be called while holding histogramsMutex
+func (l *BenchmarkRunner) recordFirstByte(class string, cmdStat CmdStat) {
+	if class == "" {
+		return
+	}
+	if _, exist := l.firstByteHistograms[class]; !exist {
+		l.firstByteHistograms[class] = hdrhistogram.New(1, 1000000, 3)
+	}
+	_ = l.firstByteHistograms[class].RecordValue(int64(cmdStat.FirstByteLatency()))
+}
+
+// countCommand accounts for a command on the overall and current reporting period class counts.
+// Must be called while holding histogramsMutex
+func (l *BenchmarkRunner) countCommand(class string) {
+	l.commandCounts[class]++
+	l.instCommandCounts[class]++
+}
+
+// classBytesCounter holds the transmitted and received bytes of a class of commands
+type classBytesCounter struct {
+	tx uint64
+	rx uint64
+}
+
+// countBytes accounts for the transmitted and received bytes of a command, on its class
+func (l *BenchmarkRunner) countBytes(class string, cmdStat CmdStat) {
+	atomic.AddUint64(&l.classBytes[class].tx, cmdStat.Tx())
+	atomic.AddUint64(&l.classBytes[class].rx, cmdStat.Rx())
+}
+
+// GetClassBytesMap returns, per class, the transmitted and received bytes
+func (b *BenchmarkRunner) GetClassBytesMap() map[string]interface{} {
+	configs := map[string]interface{}{}
+	for class, counter := range b.classBytes {
+		configs[class] = map[string]interface{}{"TxBytes": atomic.LoadUint64(&counter.tx), "RxBytes": atomic.LoadUint64(&counter.rx)}
+	}
+	return configs
+}
+
+// GetHistogramOverflowsMap returns, per class, how many latencies were above the histograms highest trackable value
+// and the fraction of the class commands they represent
+func (b *BenchmarkRunner) GetHistogramOverflowsMap() map[string]interface{} {
+	configs := map[string]interface{}{}
+	histograms := map[string]*hdrhistogram.Histogram{
+		"setupWrite":  b.setupWriteHistogram,
+		"write":       b.writeHistogram,
+		"update":      b.updateHistogram,
+		"read":        b.readHistogram,
+		"readCursor":  b.readCursorHistogram,
+		"delete":      b.deleteHistogram,
+		"allCommands": b.totalHistogram,
+	}
+	for label, hist := range b.customHistograms {
+		histograms[label] = hist
+	}
+	for class, hist := range histograms {
+		overflows := atomic.LoadUint64(b.histogramOverflows[class])
+		ratio := 0.0
+		if overflows > 0 {
+			ratio = float64(overflows) / float64(uint64(hist.TotalCount())+overflows)
+		}
+		configs[class] = map[string]interface{}{"OverflowCount": overflows, "OverflowRatio": ratio}
+	}
+	return configs
+}
+
+// GetQueryTimeoutsMap returns, per query group, the number of commands cut off by a server side query timeout
+// and their ratio over the query group commands
+func (b *BenchmarkRunner) GetQueryTimeoutsMap() map[string]interface{} {
+	configs := map[string]interface{}{}
+	b.detailedMapHistogramsMutex.RLock()
+	defer b.detailedMapHistogramsMutex.RUnlock()
+	for groupAndQuery, timeouts := range b.queryTimeouts {
+		configs[groupAndQuery] = map[string]interface{}{"QueryTimeoutCount": timeouts, "QueryTimeoutRatio": float64(timeouts) / float64(b.detailedCounts[groupAndQuery])}
+	}
+	return configs
+}
+
 // summary prints the summary of statistics from loading
 func (l *BenchmarkRunner) summary() {
 	took := l.end.Sub(l.start)
-	writeCount := l.writeHistogram.TotalCount()
-	setupWriteCount := l.setupWriteHistogram.TotalCount()
-	totalWriteCount := writeCount + setupWriteCount
-	readCount := l.readHistogram.TotalCount()
-	readCursorCount := l.readCursorHistogram.TotalCount()
-	totalReadCount := readCount + readCursorCount
-	updateCount := l.updateHistogram.TotalCount()
-	deleteCount := l.deleteHistogram.TotalCount()
-
-	totalOps := totalWriteCount + totalReadCount + updateCount + deleteCount
+	writeCount := l.commandCounts["write"]
+	setupWriteCount := l.commandCounts["setupWrite"]
+	readCount := l.commandCounts["read"]
+	readCursorCount := l.commandCounts["readCursor"]
+	updateCount := l.commandCounts["update"]
+	deleteCount := l.commandCounts["delete"]
+
+	totalOps := l.totalOps()
 	txTotalBytes := atomic.LoadUint64(&l.txTotalBytes)
 	rxTotalBytes := atomic.LoadUint64(&l.rxTotalBytes)
 
@@ -520,8 +1355,10 @@ func (l *BenchmarkRunner) summary() {
 	l.testResult.StartTime = l.start.Unix() * 1000
 	l.testResult.EndTime = l.end.Unix() * 1000
 	l.testResult.DurationMillis = took.Milliseconds()
+	l.testResult.StopReason = l.stopReason
 	l.testResult.Metadata = l.Metadata
 	l.testResult.ResultFormatVersion = CurrentResultFormatVersion
+	l.testResult.ResultType = RunResultType
 
 	fmt.Printf("\nSummary:\n")
 	fmt.Printf("Issued %d Commands in %0.3fsec with %d workers\n", totalOps, took.Seconds(), l.workers)
@@ -548,12 +1385,152 @@ func (l *BenchmarkRunner) summary() {
 		deleteRate,
 		float64(l.deleteHistogram.ValueAtQuantile(50.0))/10e2,
 	)
+	l.printLabelsSummary(took.Seconds())
+	if bytesPerDoc, exists := l.testResult.IndexMemory["BytesPerDoc"]; exists {
+		fmt.Printf("\tIndex memory: %0.1f bytes per document (%sB over %d documents)\n", bytesPerDoc,
+			bytefmt.ByteSize(uint64(l.testResult.IndexMemory["IndexMemoryBytes"].(float64))), l.testResult.IndexMemory["NumDocs"])
+	}
+	if l.stopReason == "max-error-rate" {
+		fmt.Printf("\tStopped before exhausting the input, given the error rate of %0.3f%% (%d errors out of %d commands) between %s and %s exceeded -max-error-rate %0.3f%%\n",
+			l.errorRateTrigger["ErrorRatePct"], l.errorRateTrigger["Errors"], l.errorRateTrigger["Commands"],
+			time.Unix(0, l.errorRateTrigger["StartTime"].(int64)*int64(time.Millisecond)).Format(time.RFC3339),
+			time.Unix(0, l.errorRateTrigger["EndTime"].(int64)*int64(time.Millisecond)).Format(time.RFC3339), l.maxErrorRate)
+	} else if l.stopReason == "heartbeat-threshold" {
+		fmt.Printf("\tStopped before exhausting the input, given the target database was unreachable for longer than -heartbeat-threshold %v\n", l.heartbeatThreshold)
+	} else if l.stopReason != "" {
+		fmt.Printf("\tStopped before exhausting the input, given the -%s transfer budget was reached (TX %d bytes, RX %d bytes)\n", l.stopReason, txTotalBytes, rxTotalBytes)
+	}
+	if v, exists := l.testResult.ThroughputStability["allCommands"]; exists {
+		stability := v.(map[string]interface{})
+		fmt.Printf("\tThroughput stability across %d reporting periods: stddev %0.0f ops/sec, coefficient of variation %0.3f (min %0.0f ops/sec, max %0.0f ops/sec)\n",
+			stability["Intervals"], stability["StdDevRate"], stability["CoefficientOfVariation"], stability["MinRate"], stability["MaxRate"])
+	}
 	fmt.Printf("\tOverall TX Byte Rate: %sB/sec\n", txByteRateStr)
 	fmt.Printf("\tOverall RX Byte Rate: %sB/sec\n", rxByteRateStr)
+	for _, class := range [][2]string{{"setupWrite", "Setup Writes"}, {"write", "Writes"}, {"update", "Updates"}, {"read", "Reads"}, {"readCursor", "Cursor Reads"}, {"delete", "Deletes"}} {
+		counter := l.classBytes[class[0]]
+		tx, rx := atomic.LoadUint64(&counter.tx), atomic.LoadUint64(&counter.rx)
+		if tx > 0 || rx > 0 {
+			fmt.Printf("\t- %s TX %d bytes, RX %d bytes\n", class[1], tx, rx)
+		}
+	}
+	if len(l.firstByteHistograms) > 0 {
+		fmt.Printf("\tTime to first reply byte vs full reply:\n")
+		fullHistograms := map[string]*hdrhistogram.Histogram{"allCommands": l.totalHistogram, "setupWrite": l.setupWriteHistogram, "write": l.writeHistogram, "update": l.updateHistogram, "read": l.readHistogram, "readCursor": l.readCursorHistogram, "delete": l.deleteHistogram}
+		for _, class := range [][2]string{{"allCommands", "Total"}, {"setupWrite", "Setup Writes"}, {"write", "Writes"}, {"update", "Updates"}, {"read", "Reads"}, {"readCursor", "Cursor Reads"}, {"delete", "Deletes"}} {
+			firstByte, exists := l.firstByteHistograms[class[0]]
+			if !exists {
+				continue
+			}
+			full := fullHistograms[class[0]]
+			fmt.Printf("\t- %s first byte q50 lat %0.3f ms, q99 lat %0.3f ms\tfull reply q50 lat %0.3f ms, q99 lat %0.3f ms\n", class[1],
+				float64(firstByte.ValueAtQuantile(50.0))/10e2, float64(firstByte.ValueAtQuantile(99.0))/10e2,
+				float64(full.ValueAtQuantile(50.0))/10e2, float64(full.ValueAtQuantile(99.0))/10e2)
+		}
+	}
+	if errorCount := atomic.LoadUint64(&l.errorCount); errorCount > 0 {
+		fmt.Printf("\tErrors: %d commands replied with an error (%d of them timed out)\n", errorCount, atomic.LoadUint64(&l.timedOutCount))
+	}
+	if queryTimeoutCount := atomic.LoadUint64(&l.queryTimeoutCount); queryTimeoutCount > 0 {
+		fmt.Printf("\tQuery timeouts: %d commands were cut off by a server side query timeout\n", queryTimeoutCount)
+		for groupAndQuery, v := range l.testResult.QueryTimeouts {
+			timeouts := v.(map[string]interface{})
+			fmt.Printf("\t- %s %d commands (%0.3f%%)\n", groupAndQuery, timeouts["QueryTimeoutCount"], timeouts["QueryTimeoutRatio"].(float64)*100.0)
+		}
+	}
+	for class, v := range l.testResult.HistogramOverflows {
+		overflow := v.(map[string]interface{})
+		if overflow["OverflowCount"].(uint64) > 0 {
+			fmt.Printf("\tWARNING: %d %s commands (%0.3f%%) took longer than the histograms max of %0.3f ms and were not recorded on the latency histograms\n",
+				overflow["OverflowCount"], class, overflow["OverflowRatio"].(float64)*100.0, float64(l.totalHistogram.HighestTrackableValue())/10e2)
+		}
+	}
+	fmt.Printf("\tWorkers queue depth: max %d batches, avg %0.1f batches\n", l.testResult.QueueDepth["MaxQueueDepth"], l.testResult.QueueDepth["AvgQueueDepth"])
+	if len(l.testResult.ServerInfo) > 0 {
+		printServerInfo(l.testResult.ServerInfo)
+	}
+	for _, timing := range l.testResult.Teardown {
+		if timing["Error"] != "" {
+			fmt.Printf("\tTeardown: %s failed after %0.3f ms: %s\n", timing["Command"], timing["DurationMillis"], timing["Error"])
+			continue
+		}
+		fmt.Printf("\tTeardown: %s took %0.3f ms\n", timing["Command"], timing["DurationMillis"])
+	}
+	if len(l.testResult.ClusterDistribution) > 0 {
+		nodes := make([]string, 0, len(l.testResult.ClusterDistribution))
+		clusterCommands := uint64(0)
+		for node, distribution := range l.testResult.ClusterDistribution {
+			nodes = append(nodes, node)
+			clusterCommands += distribution.(map[string]interface{})["Commands"].(uint64)
+		}
+		sort.Strings(nodes)
+		fmt.Printf("\tCluster nodes distribution:\n")
+		for _, node := range nodes {
+			distribution := l.testResult.ClusterDistribution[node].(map[string]interface{})
+			commands := distribution["Commands"].(uint64)
+			fmt.Printf("\t- %s %d commands (%0.1f%%), TX %d bytes\n", node, commands, 100.0*float64(commands)/float64(clusterCommands), distribution["TxBytes"])
+		}
+	}
+	if len(l.testResult.WorkerStagger) > 0 {
+		fmt.Printf("\tWorkers ramp-up: %d workers started, every %0.3f ms, over %0.3f ms\n", l.testResult.WorkerStagger["StartedWorkers"],
+			l.testResult.WorkerStagger["StaggerMillis"], l.testResult.WorkerStagger["RampUpMillis"])
+	}
+	if total, ok := l.testResult.Reconnects["TotalReconnects"].(uint64); ok && total > 0 {
+		fmt.Printf("\tReconnects: %d connections were re-established by %d workers\n", total, len(l.testResult.Reconnects["PerWorker"].(map[string]interface{})))
+	}
+	if len(l.testResult.Compression) > 0 {
+		fmt.Printf("\tTX compression: %d sampled commands of %d bytes compress to %d bytes with gzip (ratio %0.2f)\n", l.testResult.Compression["SampledCommands"],
+			l.testResult.Compression["SampledTxBytes"], l.testResult.Compression["CompressedTxBytes"], l.testResult.Compression["TxCompressionRatio"])
+	}
+	if len(l.testResult.Verification) > 0 {
+		fmt.Printf("\tVerification: %d commands checked, %d mismatches\n", l.testResult.Verification["TotalChecked"], l.testResult.Verification["TotalMismatches"])
+	}
+	if match, ok := l.testResult.CountVerification["Match"].(bool); ok {
+		if errStr, failed := l.testResult.CountVerification["Error"]; failed {
+			fmt.Printf("\tCount verification: FAILED, %s\n", errStr)
+		} else {
+			fmt.Printf("\tCount verification: %d documents loaded, the index num_docs increased by %d (match: %t)\n",
+				l.testResult.CountVerification["Expected"], l.testResult.CountVerification["Actual"], match)
+		}
+	}
+	if l.clientStats {
+		fmt.Printf("\tClient stats: max heap %sB, max %d goroutines, %d GCs with a total pause of %0.3f ms\n",
+			bytefmt.ByteSize(l.testResult.ClientStats["MaxHeapAllocBytes"].(uint64)), l.testResult.ClientStats["MaxGoroutines"],
+			l.testResult.ClientStats["NumGC"], l.testResult.ClientStats["GCPauseTotalMs"])
+	}
+	fmt.Printf("\tRun window: %s -> %s (unix ms %d -> %d)\n", l.start.Format(time.RFC3339), l.end.Format(time.RFC3339),
+		l.start.UnixNano()/int64(time.Millisecond), l.end.UnixNano()/int64(time.Millisecond))
+	if l.grafanaAnnotationURL != "" {
+		text := fmt.Sprintf("ftsb: %d commands with %d workers, %0.0f ops/sec", totalOps, l.workers, overallOpsRate)
+		if l.Metadata != "" {
+			text = fmt.Sprintf("%s (%s)", text, l.Metadata)
+		}
+		if err := l.pushGrafanaAnnotation(l.start, l.end, text); err != nil {
+			log.Printf("cannot push the run annotation to grafana: %v\n", err)
+		} else {
+			fmt.Printf("\tGrafana annotation pushed to %s\n", l.grafanaAnnotationURL)
+		}
+	}
+
+	if l.hdrOutFile != "" {
+		written, err := l.writeHdrOutFiles()
+		if err != nil {
+			log.Fatalf("cannot write the hdr out files: %v", err)
+		}
+		fmt.Printf("\tLatency histograms written to %s\n", strings.Join(written, ", "))
+	}
+
+	// on a concurrency sweep the results of every level are written together, once the sweep is done
+	if l.concurrencySweep == "" {
+		l.writeJsonOutFile(l.testResult)
+	}
+}
 
+// writeJsonOutFile writes the given results to -json-out-file, if set
+func (l *BenchmarkRunner) writeJsonOutFile(result interface{}) {
 	if strings.Compare(l.JsonOutFile, "") != 0 {
 
-		file, err := json.MarshalIndent(l.testResult, "", " ")
+		file, err := json.MarshalIndent(result, "", " ")
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -563,7 +1540,6 @@ func (l *BenchmarkRunner) summary() {
 			log.Fatal(err)
 		}
 	}
-
 }
 
 // report handles periodic reporting of loading stats
@@ -578,21 +1554,59 @@ func (l *BenchmarkRunner) report(period time.Duration, start time.Time, w *tabwr
 	prevTotalOps := int64(0)
 	prevTxTotalBytes := uint64(0)
 	prevRxTotalBytes := uint64(0)
-
-	fmt.Fprint(w, "setup writes/sec\twrites/sec\tupdates/sec\treads/sec\tcursor reads/sec\tdeletes/sec\tcurrent ops/sec\ttotal ops\tTX BW/s\tRX BW/s\n")
+	prevStdinRows := uint64(0)
+	prevErrorCount := uint64(0)
+
+	header := "setup writes/sec\twrites/sec\tupdates/sec\treads/sec\tcursor reads/sec\tdeletes/sec\tcurrent ops/sec\ttotal ops\tTX BW/s\tRX BW/s"
+	if l.stdinRows != nil {
+		// there is no input file size to infer the progress from, so report the rows read from STDIN
+		header += "\tstdin rows\tstdin rows/sec"
+		if l.expectedRows > 0 {
+			header += "\tprogress\tETA"
+		}
+		// terminate the last cell, so that it is aligned as well
+		header += "\t"
+	}
+	fmt.Fprint(w, header+"\n")
 	w.Flush()
-	for now := range time.NewTicker(period).C {
+	defer l.reportWg.Done()
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		var now time.Time
+		lastPeriod := false
+		select {
+		case now = <-ticker.C:
+		case <-l.reportDone:
+			now = time.Now()
+			lastPeriod = true
+		}
 		took := now.Sub(prevTime)
-		writeCount := l.writeHistogram.TotalCount()
-		setupWriteCount := l.setupWriteHistogram.TotalCount()
-		totalWriteCount := writeCount + setupWriteCount
-		readCount := l.readHistogram.TotalCount()
-		readCursorCount := l.readCursorHistogram.TotalCount()
-		totalReadCount := readCount + readCursorCount
-		updateCount := l.updateHistogram.TotalCount()
-		deleteCount := l.deleteHistogram.TotalCount()
-
-		totalOps := totalWriteCount + totalReadCount + updateCount + deleteCount
+
+		// read the counters and swap the instant histograms as a consistent snapshot, so that every command
+		// is accounted on exactly one reporting period
+		l.histogramsMutex.Lock()
+		writeCount := l.commandCounts["write"]
+		setupWriteCount := l.commandCounts["setupWrite"]
+		readCount := l.commandCounts["read"]
+		readCursorCount := l.commandCounts["readCursor"]
+		updateCount := l.commandCounts["update"]
+		deleteCount := l.commandCounts["delete"]
+		totalOps := l.totalOps()
+		errorCount := atomic.LoadUint64(&l.errorCount)
+		setupWriteQ50 := float64(l.setupWriteHistogram.ValueAtQuantile(50.0)) / 10e2
+		writeQ50 := float64(l.writeHistogram.ValueAtQuantile(50.0)) / 10e2
+		updateQ50 := float64(l.updateHistogram.ValueAtQuantile(50.0)) / 10e2
+		readQ50 := float64(l.readHistogram.ValueAtQuantile(50.0)) / 10e2
+		readCursorQ50 := float64(l.readCursorHistogram.ValueAtQuantile(50.0)) / 10e2
+		deleteQ50 := float64(l.deleteHistogram.ValueAtQuantile(50.0)) / 10e2
+		totalQ50 := float64(l.totalHistogram.ValueAtQuantile(50.0)) / 10e2
+		inst := l.swapInstantHistograms()
+		instCounts := l.instCommandCounts
+		l.instCommandCounts = make(map[string]int64)
+		l.histogramsMutex.Unlock()
+
+		l.checkErrorRate(errorRatePeriod{start: prevTime, end: now, commands: totalOps - prevTotalOps, errors: int64(errorCount - prevErrorCount)})
 		txTotalBytes := atomic.LoadUint64(&l.txTotalBytes)
 		rxTotalBytes := atomic.LoadUint64(&l.rxTotalBytes)
 		setupWriteRate := calculateRateMetrics(setupWriteCount, prevSetupWriteCount, took)
@@ -607,35 +1621,76 @@ func (l *BenchmarkRunner) report(period time.Duration, start time.Time, w *tabwr
 		txByteRateStr := bytefmt.ByteSize(uint64(overallTxByteRate))
 		rxByteRateStr := bytefmt.ByteSize(uint64(overallRxByteRate))
 
-		l.setupWriteTs = l.addRateMetricsDatapoints(l.setupWriteTs, now, took, l.inst_setupWriteHistogram)
-		l.writeTs = l.addRateMetricsDatapoints(l.writeTs, now, took, l.inst_writeHistogram)
-		l.readTs = l.addRateMetricsDatapoints(l.readTs, now, took, l.inst_readHistogram)
-		l.readCursorTs = l.addRateMetricsDatapoints(l.readCursorTs, now, took, l.inst_readCursorHistogram)
-		l.updateTs = l.addRateMetricsDatapoints(l.updateTs, now, took, l.inst_updateHistogram)
-		l.deleteTs = l.addRateMetricsDatapoints(l.deleteTs, now, took, l.inst_deleteHistogram)
+		l.setupWriteTs = l.addRateMetricsDatapoints(l.setupWriteTs, now, took, inst["setupWrite"], instCounts["setupWrite"])
+		l.writeTs = l.addRateMetricsDatapoints(l.writeTs, now, took, inst["write"], instCounts["write"])
+		l.readTs = l.addRateMetricsDatapoints(l.readTs, now, took, inst["read"], instCounts["read"])
+		l.readCursorTs = l.addRateMetricsDatapoints(l.readCursorTs, now, took, inst["readCursor"], instCounts["readCursor"])
+		l.updateTs = l.addRateMetricsDatapoints(l.updateTs, now, took, inst["update"], instCounts["update"])
+		l.deleteTs = l.addRateMetricsDatapoints(l.deleteTs, now, took, inst["delete"], instCounts["delete"])
+		if l.clientStats {
+			l.clientStatsTs = append(l.clientStatsTs, l.clientStatsSampler.sample(now))
+		}
+		fields := map[string]float64{
+			"setupWriteRate":  setupWriteRate,
+			"setupWriteQ50Ms": setupWriteQ50,
+			"writeRate":       writeRate,
+			"writeQ50Ms":      writeQ50,
+			"updateRate":      updateRate,
+			"updateQ50Ms":     updateQ50,
+			"readRate":        readRate,
+			"readQ50Ms":       readQ50,
+			"readCursorRate":  readCursorRate,
+			"readCursorQ50Ms": readCursorQ50,
+			"deleteRate":      deleteRate,
+			"deleteQ50Ms":     deleteQ50,
+			"totalRate":       CurrentOpsRate,
+			"totalQ50Ms":      totalQ50,
+			"totalOps":        float64(totalOps),
+			"txBytesRate":     overallTxByteRate,
+			"rxBytesRate":     overallRxByteRate,
+		}
+		if l.influx != nil {
+			if err := l.influx.write(now, fields); err != nil {
+				log.Printf("error while writing to influx out file %s: %v\n", l.influxOutFile, err)
+			}
+		}
+		if l.reportSocket != nil {
+			l.reportSocket.publish(now, l.workers, fields)
+		}
 
-		fmt.Fprint(w, fmt.Sprintf("%.0f (%.3f) \t%.0f (%.3f) \t%.0f (%.3f) \t%.0f (%.3f) \t%.0f (%.3f) \t%.0f (%.3f) \t %.0f (%.3f) \t%d \t %sB/s \t %sB/s\n",
+		fmt.Fprint(w, fmt.Sprintf("%.0f (%.3f) \t%.0f (%.3f) \t%.0f (%.3f) \t%.0f (%.3f) \t%.0f (%.3f) \t%.0f (%.3f) \t %.0f (%.3f) \t%d \t %sB/s \t %sB/s",
 			setupWriteRate,
-			float64(l.setupWriteHistogram.ValueAtQuantile(50.0))/10e2,
+			setupWriteQ50,
 
 			writeRate,
-			float64(l.writeHistogram.ValueAtQuantile(50.0))/10e2,
+			writeQ50,
 
 			updateRate,
-			float64(l.updateHistogram.ValueAtQuantile(50.0))/10e2,
+			updateQ50,
 
 			readRate,
-			float64(l.readHistogram.ValueAtQuantile(50.0))/10e2,
+			readQ50,
 
 			readCursorRate,
-			float64(l.readCursorHistogram.ValueAtQuantile(50.0))/10e2,
+			readCursorQ50,
 
 			deleteRate,
-			float64(l.deleteHistogram.ValueAtQuantile(50.0))/10e2,
+			deleteQ50,
 
 			CurrentOpsRate,
-			float64(l.totalHistogram.ValueAtQuantile(50.0))/10e2,
+			totalQ50,
 			totalOps, txByteRateStr, rxByteRateStr))
+		if l.stdinRows != nil {
+			stdinRows := atomic.LoadUint64(l.stdinRows)
+			stdinRowsRate := calculateRateMetrics(int64(stdinRows), int64(prevStdinRows), took)
+			fmt.Fprintf(w, " \t%d \t%.0f", stdinRows, stdinRowsRate)
+			if l.expectedRows > 0 {
+				fmt.Fprintf(w, " \t%.1f%% \t%s", 100.0*float64(stdinRows)/float64(l.expectedRows), estimateETA(stdinRows, l.expectedRows, stdinRowsRate))
+			}
+			fmt.Fprint(w, " \t")
+			prevStdinRows = stdinRows
+		}
+		fmt.Fprint(w, "\n")
 		w.Flush()
 		prevSetupWriteCount = setupWriteCount
 		prevWriteCount = writeCount
@@ -646,16 +1701,46 @@ func (l *BenchmarkRunner) report(period time.Duration, start time.Time, w *tabwr
 		prevTxTotalBytes = txTotalBytes
 		prevRxTotalBytes = rxTotalBytes
 		prevTotalOps = totalOps
+		prevErrorCount = errorCount
 		prevTime = now
+		if lastPeriod {
+			return
+		}
+	}
+}
 
-		l.inst_setupWriteHistogram.Reset()
-		l.inst_writeHistogram.Reset()
-		l.inst_readHistogram.Reset()
-		l.inst_readCursorHistogram.Reset()
-		l.inst_updateHistogram.Reset()
-		l.inst_deleteHistogram.Reset()
+// swapInstantHistograms replaces the instant histograms by empty ones, returning the previous ones per class.
+// Must be called while holding histogramsMutex
+func (l *BenchmarkRunner) swapInstantHistograms() map[string]*hdrhistogram.Histogram {
+	inst := map[string]*hdrhistogram.Histogram{
+		"setupWrite":  l.inst_setupWriteHistogram,
+		"write":       l.inst_writeHistogram,
+		"update":      l.inst_updateHistogram,
+		"read":        l.inst_readHistogram,
+		"readCursor":  l.inst_readCursorHistogram,
+		"delete":      l.inst_deleteHistogram,
+		"allCommands": l.inst_totalHistogram,
+	}
+	l.inst_setupWriteHistogram = hdrhistogram.New(1, 1000000, 3)
+	l.inst_writeHistogram = hdrhistogram.New(1, 1000000, 3)
+	l.inst_updateHistogram = hdrhistogram.New(1, 1000000, 3)
+	l.inst_readHistogram = hdrhistogram.New(1, 1000000, 3)
+	l.inst_readCursorHistogram = hdrhistogram.New(1, 1000000, 3)
+	l.inst_deleteHistogram = hdrhistogram.New(1, 1000000, 3)
+	l.inst_totalHistogram = hdrhistogram.New(1, 1000000, 3)
+	return inst
+}
 
+// estimateETA returns the estimated time to read the remaining expected rows at the given rate, or "-" if it
+// can't be estimated
+func estimateETA(rows, expectedRows uint64, rate float64) string {
+	if rows >= expectedRows {
+		return "0s"
 	}
+	if rate <= 0 {
+		return "-"
+	}
+	return time.Duration(float64(expectedRows-rows) / rate * float64(time.Second)).Round(time.Second).String()
 }
 
 // protect against NaN on json
@@ -667,17 +1752,32 @@ func wrapNaN(input float64) (output float64) {
 	return
 }
 
-func (l *BenchmarkRunner) addRateMetricsDatapoints(datapoints []DataPoint, now time.Time, timeframe time.Duration, hist *hdrhistogram.Histogram) []DataPoint {
-	ops, mp := generateQuantileMap(hist)
+func (l *BenchmarkRunner) addRateMetricsDatapoints(datapoints []DataPoint, now time.Time, timeframe time.Duration, hist *hdrhistogram.Histogram, ops int64) []DataPoint {
+	_, mp := generateQuantileMap(hist)
 	rate := 0.0
 	rate = float64(ops) / float64(timeframe.Seconds())
 	mp["rate"] = rate
-	datapoint := DataPoint{now.Unix(), mp}
+	mp["count"] = float64(ops)
+	datapoint := DataPoint{Timestamp: now.Unix(), MultiValues: mp}
+	if l.timeSeriesBuckets {
+		datapoint.Buckets = histogramBuckets(hist)
+	}
 	datapoints = append(datapoints, datapoint)
 	return datapoints
 
 }
 
+// histogramBuckets returns the non empty buckets of the histogram, in increasing latency order
+func histogramBuckets(hist *hdrhistogram.Histogram) []HistogramBucket {
+	buckets := []HistogramBucket{}
+	for _, bar := range hist.Distribution() {
+		if bar.Count > 0 {
+			buckets = append(buckets, HistogramBucket{ValueMs: float64(bar.To) / 10e2, Count: bar.Count})
+		}
+	}
+	return buckets
+}
+
 func generateQuantileMap(hist *hdrhistogram.Histogram) (int64, map[string]float64) {
 	ops := hist.TotalCount()
 	q0 := 0.0
@@ -715,6 +1815,10 @@ func (b *BenchmarkRunner) GetOverallQuantiles() map[string]interface{} {
 	configs["delete"] = delete
 	_, all := generateQuantileMap(b.totalHistogram)
 	configs["allCommands"] = all
+	for label, hist := range b.customHistograms {
+		_, quantilesMap := generateQuantileMap(hist)
+		configs[label] = quantilesMap
+	}
 
 	for k, hist := range b.detailedMapHistograms {
 		_, quantilesMap := generateQuantileMap(hist)
@@ -724,6 +1828,16 @@ func (b *BenchmarkRunner) GetOverallQuantiles() map[string]interface{} {
 	return configs
 }
 
+// GetOverallFirstByteQuantiles returns the time to the first reply byte quantiles, per class
+func (b *BenchmarkRunner) GetOverallFirstByteQuantiles() map[string]interface{} {
+	configs := map[string]interface{}{}
+	for class, hist := range b.firstByteHistograms {
+		_, quantilesMap := generateQuantileMap(hist)
+		configs[class] = quantilesMap
+	}
+	return configs
+}
+
 func calculateRateMetrics(current, prev int64, took time.Duration) (rate float64) {
 	rate = float64(current-prev) / float64(took.Seconds())
 	return
@@ -0,0 +1,179 @@
+package benchmark_runner
+
+import (
+	"bufio"
+	"flag"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// fakeBenchmark issues nothing: each input row ( <command type>,<query id> ) is accounted as a command of its
+// command type, replied after a fixed latency
+type fakeBenchmark struct {
+	// time taken to process each batch, so that the workload spans several reporting periods
+	batchDelay time.Duration
+}
+
+type fakeDecoder struct {
+	scanner *bufio.Scanner
+}
+
+func (d *fakeDecoder) Decode(_ *bufio.Reader) *DocHolder {
+	if !d.scanner.Scan() {
+		return nil
+	}
+	return NewDocument(d.scanner.Text())
+}
+
+type fakeBatch struct {
+	rows []string
+}
+
+func (b *fakeBatch) Len() int {
+	return len(b.rows)
+}
+
+func (b *fakeBatch) Append(item *DocHolder) {
+	b.rows = append(b.rows, item.Data.(string))
+}
+
+type fakeBatchFactory struct{}
+
+func (f *fakeBatchFactory) New() Batch {
+	return &fakeBatch{}
+}
+
+type fakeIndexer struct {
+	partitions uint
+}
+
+func (i *fakeIndexer) GetIndex(itemsRead uint64, _ *DocHolder) int {
+	return int(itemsRead % uint64(i.partitions))
+}
+
+type fakeProcessor struct {
+	batchDelay time.Duration
+}
+
+func (p *fakeProcessor) Init(_ int, _ bool, _ int) {}
+
+func (p *fakeProcessor) ProcessBatch(b Batch, _ bool, _ *rate.Limiter, _ bool) Stat {
+	time.Sleep(p.batchDelay)
+	stat := NewStat()
+	for _, row := range b.(*fakeBatch).rows {
+		fields := strings.SplitN(row, ",", 2)
+		stat.AddEntry([]byte(fields[0]), []byte(fields[1]), uint64(time.Now().Unix()), 100, false, false, 10, 10)
+	}
+	return *stat
+}
+
+func (b *fakeBenchmark) GetCmdDecoder(br *bufio.Reader) DocDecoder {
+	return &fakeDecoder{scanner: bufio.NewScanner(br)}
+}
+
+func (b *fakeBenchmark) GetBatchFactory() BatchFactory {
+	return &fakeBatchFactory{}
+}
+
+func (b *fakeBenchmark) GetCommandIndexer(maxPartitions uint) DocIndexer {
+	return &fakeIndexer{partitions: maxPartitions}
+}
+
+func (b *fakeBenchmark) GetProcessor() Processor {
+	return &fakeProcessor{batchDelay: b.batchDelay}
+}
+
+func (b *fakeBenchmark) GetConfigurationParametersMap() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+var registerFlagsOnce sync.Once
+
+// flagsRunner returns the singleton runner, registering its flags on the first call
+func flagsRunner() *BenchmarkRunner {
+	registerFlagsOnce.Do(func() {
+		GetBenchmarkRunner()
+	})
+	return loader
+}
+
+func setFlag(t *testing.T, name, value string) {
+	if err := flag.Set(name, value); err != nil {
+		t.Fatalf("cannot set -%s: %v", name, err)
+	}
+}
+
+func TestJsonConfigAndOutFilesAreDistinct(t *testing.T) {
+	l := flagsRunner()
+	defer setFlag(t, "json-config-file", "")
+	defer setFlag(t, "json-out-file", "")
+
+	setFlag(t, "json-config-file", "suite.json")
+	if l.JsonConfigFile != "suite.json" || l.JsonOutFile != "" {
+		t.Fatalf("setting -json-config-file only: got JsonConfigFile %q and JsonOutFile %q", l.JsonConfigFile, l.JsonOutFile)
+	}
+	setFlag(t, "json-out-file", "results.json")
+	if l.JsonConfigFile != "suite.json" || l.JsonOutFile != "results.json" {
+		t.Fatalf("setting both flags: got JsonConfigFile %q and JsonOutFile %q", l.JsonConfigFile, l.JsonOutFile)
+	}
+
+	if sameFile(l.JsonConfigFile, l.JsonOutFile) {
+		t.Errorf("expected %q and %q not to be the same file", l.JsonConfigFile, l.JsonOutFile)
+	}
+
+	l.collectResults(&fakeBenchmark{})
+	runConfig := l.testResult.RunConfig
+	if runConfig["json-config-file"] != "suite.json" {
+		t.Errorf("expected the results json-config-file to be suite.json, got %v", runConfig["json-config-file"])
+	}
+	if runConfig["json-out-file"] != "results.json" {
+		t.Errorf("expected the results json-out-file to be results.json, got %v", runConfig["json-out-file"])
+	}
+}
+
+func TestReportingPeriodCountsAddUpToTotals(t *testing.T) {
+	cmdTypes := []string{"SETUP_WRITE", "WRITE", "UPDATE", "READ", "CURSOR_READ", "DELETE"}
+	var input strings.Builder
+	for n := 0; n < 20000; n++ {
+		input.WriteString(cmdTypes[n%len(cmdTypes)] + ",q\n")
+	}
+	l := newBenchmarkRunner()
+	l.workers = 4
+	l.doLoad = true
+	l.sampleRate = 1.0
+	l.reportingPeriod = time.Millisecond
+	l.br = bufio.NewReader(strings.NewReader(input.String()))
+	// the workers keep recording while the reporting periods are snapshotted
+	l.runWorkload(&fakeBenchmark{batchDelay: 200 * time.Microsecond}, WorkerPerQueue)
+
+	totals := l.GetTotalsMap()
+	classes := map[string][]DataPoint{
+		"SetupWrites": l.setupWriteTs,
+		"Writes":      l.writeTs,
+		"Updates":     l.updateTs,
+		"Reads":       l.readTs,
+		"ReadsCursor": l.readCursorTs,
+		"Deletes":     l.deleteTs,
+	}
+	periodsTotal := int64(0)
+	for class, datapoints := range classes {
+		if len(datapoints) < 2 {
+			t.Fatalf("expected the workload to span several reporting periods, got %d", len(datapoints))
+		}
+		classTotal := int64(0)
+		for _, datapoint := range datapoints {
+			classTotal += int64(datapoint.MultiValues["count"])
+		}
+		if classTotal != totals[class].(int64) {
+			t.Errorf("%s: the reporting periods account for %d commands, while the total is %d", class, classTotal, totals[class])
+		}
+		periodsTotal += classTotal
+	}
+	if periodsTotal != totals["TotalOps"].(int64) || periodsTotal != 20000 {
+		t.Errorf("the reporting periods account for %d commands, while the total is %d ( of 20000 issued )", periodsTotal, totals["TotalOps"])
+	}
+}
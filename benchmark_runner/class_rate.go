@@ -0,0 +1,49 @@
+package benchmark_runner
+
+import (
+	"flag"
+	"fmt"
+
+	"golang.org/x/time/rate"
+)
+
+// classRateLimitFlags are the per command type rate limit flags, in the order they are registered
+var classRateLimitFlags = []struct {
+	name    string
+	cmdType string
+}{
+	{"rate-limit-setup-write", "SETUP_WRITE"},
+	{"rate-limit-write", "WRITE"},
+	{"rate-limit-update", "UPDATE"},
+	{"rate-limit-read", "READ"},
+	{"rate-limit-cursor-read", "CURSOR_READ"},
+	{"rate-limit-delete", "DELETE"},
+}
+
+// registerClassRateLimitFlags registers a -rate-limit-<class> flag per built-in command type
+func (l *BenchmarkRunner) registerClassRateLimitFlags() {
+	l.classMaxRPS = map[string]*uint64{}
+	for _, f := range classRateLimitFlags {
+		l.classMaxRPS[f.cmdType] = new(uint64)
+		flag.Uint64Var(l.classMaxRPS[f.cmdType], f.name, 0, fmt.Sprintf("Limit the rate of the %s commands to this number of commands per second, overriding -max-rps for them, while the other command types keep the -max-rps limit, or their own (0 = no limit of its own).", f.cmdType))
+	}
+}
+
+// newClassRateLimiters creates a token bucket per command type with a rate limit of its own. As with -max-rps,
+// the burst allows every worker to issue a command at once
+func (l *BenchmarkRunner) newClassRateLimiters() map[string]*rate.Limiter {
+	limiters := map[string]*rate.Limiter{}
+	for cmdType, maxRPS := range l.classMaxRPS {
+		if *maxRPS > 0 {
+			limiters[cmdType] = rate.NewLimiter(rate.Limit(*maxRPS), int(l.workers))
+		}
+	}
+	return limiters
+}
+
+// ClassRateLimiter returns the rate limiter of a command type when it has a rate limit of its own ( via
+// -rate-limit-<class> ), or nil otherwise, in which case the -max-rps limit applies. The processors should wait on
+// it before issuing each command of that type
+func (l *BenchmarkRunner) ClassRateLimiter(cmdType string) *rate.Limiter {
+	return l.classLimiters[cmdType]
+}
@@ -0,0 +1,55 @@
+package benchmark_runner
+
+import (
+	"runtime"
+	"time"
+)
+
+// clientStatsSampler samples the benchmark client runtime memory and GC stats,
+// enabling to distinguish client side limits from server side ones
+type clientStatsSampler struct {
+	prevNumGC        uint32
+	prevPauseTotalNs uint64
+	maxHeapAlloc     uint64
+	maxGoroutines    int
+}
+
+// sample reads the runtime stats and returns them as a DataPoint.
+// GC counts and pauses are relative to the previous sample
+func (s *clientStatsSampler) sample(now time.Time) DataPoint {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	goroutines := runtime.NumGoroutine()
+	if m.HeapAlloc > s.maxHeapAlloc {
+		s.maxHeapAlloc = m.HeapAlloc
+	}
+	if goroutines > s.maxGoroutines {
+		s.maxGoroutines = goroutines
+	}
+	mp := map[string]float64{
+		"heapAllocBytes": float64(m.HeapAlloc),
+		"heapSysBytes":   float64(m.HeapSys),
+		"numGC":          float64(m.NumGC - s.prevNumGC),
+		"gcPauseMs":      float64(m.PauseTotalNs-s.prevPauseTotalNs) / 1e6,
+		"goroutines":     float64(goroutines),
+	}
+	s.prevNumGC = m.NumGC
+	s.prevPauseTotalNs = m.PauseTotalNs
+	return DataPoint{Timestamp: now.Unix(), MultiValues: mp}
+}
+
+// GetClientStatsMap returns the overall benchmark client memory and GC stats
+func (b *BenchmarkRunner) GetClientStatsMap() map[string]interface{} {
+	configs := map[string]interface{}{}
+	if !b.clientStats {
+		return configs
+	}
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	configs["MaxHeapAllocBytes"] = b.clientStatsSampler.maxHeapAlloc
+	configs["MaxGoroutines"] = b.clientStatsSampler.maxGoroutines
+	configs["TotalAllocBytes"] = m.TotalAlloc
+	configs["NumGC"] = m.NumGC
+	configs["GCPauseTotalMs"] = float64(m.PauseTotalNs) / 1e6
+	return configs
+}
@@ -0,0 +1,158 @@
+package benchmark_runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// comparedQuantiles are the latency quantiles compared between results. q0 and q100 are left out given
+// they are single samples, and too noisy to flag regressions
+var comparedQuantiles = []string{"q50", "q95", "q99", "q999"}
+
+// legacyResultFormatVersion is the format of the results written before the ResultType ( and the run ID, tags, run
+// config and the other fields of the 0.2 format ) were recorded. They are read back as single run results, lacking
+// the fields added since
+const legacyResultFormatVersion = "0.1"
+
+// supportedResultFormatVersions are the result format versions that can be read back, so that the results written by
+// a newer ( and possibly incompatible ) ftsb are not silently misinterpreted
+var supportedResultFormatVersions = []string{legacyResultFormatVersion, CurrentResultFormatVersion}
+
+func isSupportedResultFormatVersion(version string) bool {
+	for _, supported := range supportedResultFormatVersions {
+		if version == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadTestResult reads the results of a previous run, as written to -json-out-file, rejecting the ones whose
+// ResultFormatVersion is missing or not supported
+func ReadTestResult(fileName string) (result TestResult, err error) {
+	file, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return
+	}
+	var header struct {
+		ResultFormatVersion *string `json:"ResultFormatVersion"`
+		ResultType          string  `json:"ResultType"`
+	}
+	if err = json.Unmarshal(file, &header); err != nil {
+		return
+	}
+	if header.ResultFormatVersion == nil || *header.ResultFormatVersion == "" {
+		err = fmt.Errorf("missing ResultFormatVersion. %s is not a ftsb -json-out-file result", fileName)
+		return
+	}
+	version := *header.ResultFormatVersion
+	if !isSupportedResultFormatVersion(version) {
+		err = fmt.Errorf("unsupported ResultFormatVersion %s on %s (supported: %s). The results were written by an incompatible ftsb version", version, fileName, strings.Join(supportedResultFormatVersions, ", "))
+		return
+	}
+	if version == legacyResultFormatVersion {
+		header.ResultType = RunResultType
+	}
+	switch header.ResultType {
+	case RunResultType:
+	case ConcurrencySweepResultType:
+		err = fmt.Errorf("%s holds the results of a -concurrency-sweep, and not of a single run", fileName)
+		return
+	case "":
+		err = fmt.Errorf("missing ResultType on %s, required since the ResultFormatVersion %s", fileName, CurrentResultFormatVersion)
+		return
+	default:
+		err = fmt.Errorf("unsupported ResultType %s on %s. The results were written by an incompatible ftsb version", header.ResultType, fileName)
+		return
+	}
+	if err = json.Unmarshal(file, &result); err != nil {
+		return
+	}
+	result.ResultType = header.ResultType
+	return
+}
+
+// percentChange returns the change from baseline to comparison, in percent, and false if it can not be computed
+func percentChange(baseline, comparison float64) (float64, bool) {
+	if baseline == 0 {
+		return 0, false
+	}
+	return 100.0 * (comparison - baseline) / baseline, true
+}
+
+// CompareTestResults writes the percent change from baseline to comparison of the ops/sec rates ( higher is better )
+// and each class latency quantiles ( lower is better ), flagging as regressions the changes for the worse beyond
+// threshold percent. It returns the number of regressions
+func CompareTestResults(baseline, comparison TestResult, threshold float64, out io.Writer) (regressions int) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	compare := func(metric string, baselineV, comparisonV float64, higherIsBetter bool) {
+		if baselineV == 0 && comparisonV == 0 {
+			return
+		}
+		change, ok := percentChange(baselineV, comparisonV)
+		changeStr := "n/a"
+		marker := ""
+		if ok {
+			changeStr = fmt.Sprintf("%+.2f%%", change)
+			if (higherIsBetter && change < -threshold) || (!higherIsBetter && change > threshold) {
+				marker = "REGRESSION"
+				regressions++
+			}
+		}
+		fmt.Fprintf(w, "%s\t%.3f\t%.3f\t%s\t%s\n", metric, baselineV, comparisonV, changeStr, marker)
+	}
+	fmt.Fprintf(w, "metric\tbaseline\tcomparison\tchange\t\n")
+
+	rates := make([]string, 0)
+	for k := range baseline.OverallRates {
+		// the bytes rates are not a measure of performance
+		if strings.HasSuffix(k, "Rate") && !strings.HasSuffix(k, "ByteRate") {
+			if _, exists := comparison.OverallRates[k]; exists {
+				rates = append(rates, k)
+			}
+		}
+	}
+	sort.Strings(rates)
+	for _, k := range rates {
+		compare(k+" (ops/sec)", toFloat64(baseline.OverallRates[k]), toFloat64(comparison.OverallRates[k]), true)
+	}
+
+	classes := make([]string, 0)
+	for class := range baseline.OverallQuantiles {
+		if _, exists := comparison.OverallQuantiles[class]; exists {
+			classes = append(classes, class)
+		}
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		baselineQ, okB := baseline.OverallQuantiles[class].(map[string]interface{})
+		comparisonQ, okC := comparison.OverallQuantiles[class].(map[string]interface{})
+		if !okB || !okC {
+			continue
+		}
+		for _, q := range comparedQuantiles {
+			compare(fmt.Sprintf("%s %s (ms)", class, q), toFloat64(baselineQ[q]), toFloat64(comparisonQ[q]), false)
+		}
+	}
+	// the index memory per document tracks the footprint across schema variations, when reported on both results
+	baselineBPD, okB := baseline.IndexMemory["BytesPerDoc"]
+	comparisonBPD, okC := comparison.IndexMemory["BytesPerDoc"]
+	if okB && okC {
+		compare("index memory per doc (bytes)", toFloat64(baselineBPD), toFloat64(comparisonBPD), false)
+	}
+	w.Flush()
+	return
+}
+
+// toFloat64 converts a decoded json number to float64, returning 0 for anything else
+func toFloat64(v interface{}) float64 {
+	if f, ok := v.(float64); ok {
+		return f
+	}
+	return 0
+}
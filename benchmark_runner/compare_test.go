@@ -0,0 +1,65 @@
+package benchmark_runner
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeResultFile writes contents to a file on dir, returning its name
+func writeResultFile(t *testing.T, dir, name, contents string) string {
+	fileName := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(fileName, []byte(contents), 0644); err != nil {
+		t.Fatalf("cannot write %s: %v", fileName, err)
+	}
+	return fileName
+}
+
+func TestReadTestResultRejectsConcurrencySweeps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ftsb-compare")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	run := writeResultFile(t, dir, "run.json", `{"ResultFormatVersion":"`+CurrentResultFormatVersion+`","ResultType":"run","Workers":8}`)
+	if result, err := ReadTestResult(run); err != nil || result.Workers != 8 {
+		t.Errorf("expected the single run results to be read, got %+v ( error %v )", result, err)
+	}
+	// the ResultType tells the sweep apart, regardless of the fields present
+	sweep := writeResultFile(t, dir, "sweep.json", `{"ResultFormatVersion":"`+CurrentResultFormatVersion+`","ResultType":"concurrency-sweep"}`)
+	if _, err := ReadTestResult(sweep); err == nil || !strings.Contains(err.Error(), "-concurrency-sweep") {
+		t.Errorf("expected the -concurrency-sweep results to be rejected, got error %v", err)
+	}
+	unknown := writeResultFile(t, dir, "unknown.json", `{"ResultFormatVersion":"`+CurrentResultFormatVersion+`","ResultType":"other","Levels":[]}`)
+	if _, err := ReadTestResult(unknown); err == nil || !strings.Contains(err.Error(), "unsupported ResultType") {
+		t.Errorf("expected an unknown ResultType to be rejected, got error %v", err)
+	}
+}
+
+func TestReadTestResultResultFormatVersions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ftsb-compare")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	legacy := writeResultFile(t, dir, "legacy.json", `{"ResultFormatVersion":"0.1","Workers":8}`)
+	if result, err := ReadTestResult(legacy); err != nil || result.Workers != 8 || result.ResultType != RunResultType {
+		t.Errorf("expected the 0.1 results to be read as single run results, got %+v ( error %v )", result, err)
+	}
+	untyped := writeResultFile(t, dir, "untyped.json", `{"ResultFormatVersion":"`+CurrentResultFormatVersion+`","Workers":8}`)
+	if _, err := ReadTestResult(untyped); err == nil || !strings.Contains(err.Error(), "missing ResultType") {
+		t.Errorf("expected the %s results without a ResultType to be rejected, got error %v", CurrentResultFormatVersion, err)
+	}
+	newer := writeResultFile(t, dir, "newer.json", `{"ResultFormatVersion":"9.9","ResultType":"run"}`)
+	if _, err := ReadTestResult(newer); err == nil || !strings.Contains(err.Error(), "unsupported ResultFormatVersion") {
+		t.Errorf("expected an unsupported ResultFormatVersion to be rejected, got error %v", err)
+	}
+	missing := writeResultFile(t, dir, "missing.json", `{"ResultType":"run"}`)
+	if _, err := ReadTestResult(missing); err == nil || !strings.Contains(err.Error(), "missing ResultFormatVersion") {
+		t.Errorf("expected a missing ResultFormatVersion to be rejected, got error %v", err)
+	}
+}
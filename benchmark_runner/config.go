@@ -0,0 +1,97 @@
+package benchmark_runner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// BenchmarkConfig is the subset of the benchmark suite specification (the *.cfg.json file produced
+// alongside the input files by the data generators) used by the benchmark runner
+type BenchmarkConfig struct {
+	Setup    BenchmarkStageConfig `json:"setup"`
+	Teardown BenchmarkStageConfig `json:"teardown"`
+}
+
+// BenchmarkStageConfig describes the commands to be issued on a given stage
+type BenchmarkStageConfig struct {
+	Commands [][]interface{} `json:"commands"`
+}
+
+// ReadBenchmarkConfig reads and parses a benchmark suite specification file
+func ReadBenchmarkConfig(fileName string) (config BenchmarkConfig, err error) {
+	file, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(file, &config)
+	return
+}
+
+// commandArgs converts a command from the config file into its string arguments.
+// Some generators quote the arguments so that they can be fed to a shell, so we strip the surrounding quotes.
+func commandArgs(cmd []interface{}) []string {
+	args := make([]string, 0, len(cmd))
+	for _, arg := range cmd {
+		argStr := fmt.Sprint(arg)
+		if len(argStr) >= 2 && strings.HasPrefix(argStr, "\"") && strings.HasSuffix(argStr, "\"") {
+			argStr = argStr[1 : len(argStr)-1]
+		}
+		args = append(args, argStr)
+	}
+	return args
+}
+
+// args returns the string arguments of each of the stage commands
+func (s BenchmarkStageConfig) args() [][]string {
+	commands := make([][]string, 0, len(s.Commands))
+	for _, cmd := range s.Commands {
+		commands = append(commands, commandArgs(cmd))
+	}
+	return commands
+}
+
+// issueStageCommands issues, in order, all commands of a given stage. Errors are fatal if failOnError is set.
+// It returns the command, duration in milliseconds and error ( if any ) of each issued command
+func issueStageCommands(issuer CommandIssuer, stage string, commands [][]string, failOnError bool) []map[string]interface{} {
+	timings := make([]map[string]interface{}, 0, len(commands))
+	for _, args := range commands {
+		if len(args) == 0 {
+			continue
+		}
+		log.Printf("Issuing %s command: %s", stage, strings.Join(args, " "))
+		start := time.Now()
+		err := issuer.IssueCommand(args)
+		timing := map[string]interface{}{
+			"Command":        strings.Join(args, " "),
+			"DurationMillis": float64(time.Since(start).Microseconds()) / 10e2,
+			"Error":          "",
+		}
+		if err != nil {
+			if failOnError {
+				log.Fatalf("%s command %s failed: %v", stage, strings.Join(args, " "), err)
+			}
+			log.Printf("%s command %s failed: %v", stage, strings.Join(args, " "), err)
+			timing["Error"] = err.Error()
+		}
+		timings = append(timings, timing)
+	}
+	return timings
+}
+
+// sameFile returns true if both (non empty) file names resolve to the same path
+func sameFile(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return absA == absB
+}
@@ -1,5 +1,7 @@
 package benchmark_runner
 
+import "sync/atomic"
+
 // duplexChannel acts as a two-way channel for communicating from a scan routine
 // to a worker goroutine. The toWorker channel sends databuild to the worker for it
 // to process and the toScan channel allows the worker to acknowledge completion.
@@ -7,6 +9,11 @@ package benchmark_runner
 type duplexChannel struct {
 	toWorker  chan Batch
 	toScanner chan bool
+
+	// queue depth accounting, sampled by the scanner on every dispatched batch
+	maxQueueDepth     uint64
+	totalQueueDepth   uint64
+	queueDepthSamples uint64
 }
 
 // newDuplexChannel returns a duplexChannel with specified buffer sizes
@@ -27,6 +34,16 @@ func (dc *duplexChannel) sendToScanner() {
 	dc.toScanner <- true
 }
 
+// recordQueueDepth samples the number of batches waiting to be processed by the worker(s)
+func (dc *duplexChannel) recordQueueDepth(depth int) {
+	d := uint64(depth)
+	if d > atomic.LoadUint64(&dc.maxQueueDepth) {
+		atomic.StoreUint64(&dc.maxQueueDepth, d)
+	}
+	atomic.AddUint64(&dc.totalQueueDepth, d)
+	atomic.AddUint64(&dc.queueDepthSamples, 1)
+}
+
 // close closes down the duplexChannel
 func (dc *duplexChannel) close() {
 	close(dc.toWorker)
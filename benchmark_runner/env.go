@@ -0,0 +1,43 @@
+package benchmark_runner
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EnvPrefix is the prefix of the environment variables that can be used to set the flags
+const EnvPrefix = "FTSB_"
+
+// EnvVarName returns the environment variable that sets a given flag: the flag name in upper case,
+// with dashes replaced by underscores, prefixed by EnvPrefix ( e.g. -max-rps is set by FTSB_MAX_RPS )
+func EnvVarName(flagName string) string {
+	return EnvPrefix + strings.ToUpper(strings.Replace(flagName, "-", "_", -1))
+}
+
+// ApplyEnvOverrides sets every flag that was not passed on the command line from its environment variable,
+// if defined. Must be called after flag.Parse(), so that the command line takes precedence.
+// Returns the names of the flags set from the environment
+func ApplyEnvOverrides() (applied []string, err error) {
+	setOnCLI := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		setOnCLI[f.Name] = true
+	})
+	flag.VisitAll(func(f *flag.Flag) {
+		if err != nil || setOnCLI[f.Name] {
+			return
+		}
+		envName := EnvVarName(f.Name)
+		value, defined := os.LookupEnv(envName)
+		if !defined {
+			return
+		}
+		if setErr := f.Value.Set(value); setErr != nil {
+			err = fmt.Errorf("invalid value for %s: %v", envName, setErr)
+			return
+		}
+		applied = append(applied, f.Name)
+	})
+	return
+}
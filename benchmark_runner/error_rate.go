@@ -0,0 +1,92 @@
+package benchmark_runner
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// errorRatePeriod holds the commands and errors of a reporting period
+type errorRatePeriod struct {
+	start    time.Time
+	end      time.Time
+	commands int64
+	errors   int64
+}
+
+// errorRateWindow tracks the error rate over the reporting periods of the last -error-rate-window, so that a
+// sustained error rate ( a systemic failure ) can be told apart from a burst of recoverable errors
+type errorRateWindow struct {
+	window  time.Duration
+	periods []errorRatePeriod
+}
+
+// add accounts for a reporting period, discarding the oldest ones while the remaining still span the window
+func (w *errorRateWindow) add(period errorRatePeriod) {
+	w.periods = append(w.periods, period)
+	for len(w.periods) > 1 && period.end.Sub(w.periods[1].start) >= w.window {
+		w.periods = w.periods[1:]
+	}
+}
+
+// totals returns the commands and errors of the periods within the window, alongside its time span
+func (w *errorRateWindow) totals() (start time.Time, end time.Time, commands int64, errors int64) {
+	if len(w.periods) == 0 {
+		return
+	}
+	start = w.periods[0].start
+	end = w.periods[len(w.periods)-1].end
+	for _, period := range w.periods {
+		commands += period.commands
+		errors += period.errors
+	}
+	return
+}
+
+// checkErrorRate accounts for a reporting period, flagging the benchmark to stop if the error rate over the last
+// -error-rate-window exceeds -max-error-rate. The window is only checked once the benchmark ran for its full length
+func (l *BenchmarkRunner) checkErrorRate(period errorRatePeriod) {
+	if l.maxErrorRate <= 0 || atomic.LoadInt32(&l.errorRateExceeded) == 1 {
+		return
+	}
+	l.errorRate.add(period)
+	if period.end.Sub(l.start) < l.errorRate.window {
+		return
+	}
+	start, end, commands, errors := l.errorRate.totals()
+	if commands == 0 {
+		return
+	}
+	ratePct := 100.0 * float64(errors) / float64(commands)
+	if ratePct <= l.maxErrorRate {
+		return
+	}
+	l.errorRateTrigger = map[string]interface{}{
+		"StartTime":    start.UnixNano() / int64(time.Millisecond),
+		"EndTime":      end.UnixNano() / int64(time.Millisecond),
+		"Commands":     commands,
+		"Errors":       errors,
+		"ErrorRatePct": ratePct,
+	}
+	log.Printf("error rate of %0.3f%% (%d errors out of %d commands) between %s and %s exceeded -max-error-rate %0.3f%%. Stopping the benchmark\n",
+		ratePct, errors, commands, start.Format(time.RFC3339), end.Format(time.RFC3339), l.maxErrorRate)
+	atomic.StoreInt32(&l.errorRateExceeded, 1)
+}
+
+// parseErrorRateFlags validates -max-error-rate and -error-rate-window
+func (l *BenchmarkRunner) parseErrorRateFlags() error {
+	if l.maxErrorRate == 0 {
+		return nil
+	}
+	if l.maxErrorRate < 0 || l.maxErrorRate > 100 {
+		return fmt.Errorf("-max-error-rate must be within ]0,100]. Got %f", l.maxErrorRate)
+	}
+	if l.reportingPeriod <= 0 {
+		return fmt.Errorf("-max-error-rate relies on the per reporting period error counts, so it requires a positive -reporting-period")
+	}
+	if l.errorRate.window < l.reportingPeriod {
+		return fmt.Errorf("-error-rate-window (%s) must be at least one -reporting-period (%s)", l.errorRate.window, l.reportingPeriod)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package benchmark_runner
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// grafanaAnnotationTimeout bounds the annotation request, so that an unreachable Grafana does not hang the benchmark
+const grafanaAnnotationTimeout = 10 * time.Second
+
+// grafanaAnnotation is the body of a Grafana region annotation, spanning from Time to TimeEnd ( in milliseconds )
+type grafanaAnnotation struct {
+	Time    int64    `json:"time"`
+	TimeEnd int64    `json:"timeEnd"`
+	Tags    []string `json:"tags"`
+	Text    string   `json:"text"`
+}
+
+// parseGrafanaAnnotationTags returns the comma separated list of -grafana-annotation-tags
+func parseGrafanaAnnotationTags(tagsStr string) []string {
+	tags := []string{}
+	for _, tag := range strings.Split(tagsStr, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// pushGrafanaAnnotation posts the benchmark window as a region annotation to -grafana-annotation-url ( the Grafana
+// /api/annotations endpoint ), so that the run shows up on the server side metrics graphs
+func (l *BenchmarkRunner) pushGrafanaAnnotation(start, end time.Time, text string) error {
+	body, err := json.Marshal(grafanaAnnotation{
+		Time:    start.UnixNano() / int64(time.Millisecond),
+		TimeEnd: end.UnixNano() / int64(time.Millisecond),
+		Tags:    parseGrafanaAnnotationTags(l.grafanaAnnotationTags),
+		Text:    text,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, l.grafanaAnnotationURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if l.grafanaAPIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+l.grafanaAPIKey)
+	}
+	client := http.Client{Timeout: grafanaAnnotationTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		reply, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s replied with %s: %s", l.grafanaAnnotationURL, resp.Status, strings.TrimSpace(string(reply)))
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package benchmark_runner
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// hdrOutFileName returns the -hdr-out-file name of a class, by inserting the class name before the file extension
+// ( e.g. results.hdr holds the read histogram on results.read.hdr )
+func hdrOutFileName(fileName, class string) string {
+	ext := filepath.Ext(fileName)
+	return fmt.Sprintf("%s.%s%s", strings.TrimSuffix(fileName, ext), class, ext)
+}
+
+// writeHdrOutFiles writes the final latency histogram of each class with recorded commands to its own -hdr-out-file,
+// using the HdrHistogram V2 compressed binary encoding ( values in microseconds ). It returns the written file names
+func (l *BenchmarkRunner) writeHdrOutFiles() ([]string, error) {
+	histograms := map[string]*hdrhistogram.Histogram{
+		"allCommands": l.totalHistogram,
+		"setupWrite":  l.setupWriteHistogram,
+		"write":       l.writeHistogram,
+		"update":      l.updateHistogram,
+		"read":        l.readHistogram,
+		"readCursor":  l.readCursorHistogram,
+		"delete":      l.deleteHistogram,
+	}
+	for label, hist := range l.customHistograms {
+		histograms[label] = hist
+	}
+	classes := make([]string, 0, len(histograms))
+	for class, hist := range histograms {
+		if hist.TotalCount() > 0 {
+			classes = append(classes, class)
+		}
+	}
+	sort.Strings(classes)
+	written := make([]string, 0, len(classes))
+	for _, class := range classes {
+		encoded, err := histograms[class].Encode(hdrhistogram.V2CompressedEncodingCookieBase)
+		if err != nil {
+			return written, fmt.Errorf("cannot encode the %s histogram: %v", class, err)
+		}
+		// the library encodes to base64, as used on the histogram logs, while the binary files hold the raw encoding
+		raw, err := base64.StdEncoding.DecodeString(string(encoded))
+		if err != nil {
+			return written, fmt.Errorf("cannot encode the %s histogram: %v", class, err)
+		}
+		fileName := hdrOutFileName(l.hdrOutFile, class)
+		if err = ioutil.WriteFile(fileName, raw, 0644); err != nil {
+			return written, err
+		}
+		written = append(written, fileName)
+	}
+	return written, nil
+}
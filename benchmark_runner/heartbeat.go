@@ -0,0 +1,64 @@
+package benchmark_runner
+
+import (
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// heartbeat periodically checks that the target database is reachable. If it stays unreachable for longer than
+// threshold it flags the benchmark to stop, so that the scanner stops reading the input and the workers drop the
+// batches already dispatched, and closes aborted. The results collected so far are then summarized as usual, and the
+// benchmark exits with a non zero status
+func (l *BenchmarkRunner) heartbeat(hc HealthChecker, interval, threshold time.Duration, done, aborted chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	lastOk := time.Now()
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			err := hc.Ping(interval)
+			if err == nil {
+				lastOk = now
+				continue
+			}
+			unreachable := time.Since(lastOk)
+			log.Printf("heartbeat failed: %v (unreachable for %v)", err, unreachable.Round(time.Millisecond))
+			if unreachable >= threshold {
+				log.Printf("target database unreachable for longer than %v. Aborting the benchmark with partial results", threshold)
+				atomic.StoreInt32(&l.heartbeatExceeded, 1)
+				close(aborted)
+				return
+			}
+		}
+	}
+}
+
+// HeartbeatAborted returns true once the benchmark was aborted given the target database was unreachable for longer
+// than -heartbeat-threshold. The errors of the commands still in flight are then expected, and should be accounted
+// rather than exiting before the partial results are summarized
+func (l *BenchmarkRunner) HeartbeatAborted() bool {
+	return atomic.LoadInt32(&l.heartbeatExceeded) == 1
+}
+
+// waitForWorkers waits for the workers to be done. Once the benchmark is aborted the workers still waiting on the
+// unreachable target database ( up until their connection timeout ) are waited for at most -heartbeat-threshold,
+// after which they are abandoned, no longer accounting for their commands, so that the partial results can be
+// summarized
+func (l *BenchmarkRunner) waitForWorkers(workersDone, aborted chan struct{}) {
+	select {
+	case <-workersDone:
+		return
+	case <-aborted:
+	}
+	select {
+	case <-workersDone:
+	case <-time.After(l.heartbeatThreshold):
+		log.Printf("the workers are still waiting on the target database %v after the abort. Summarizing the partial results without them", l.heartbeatThreshold)
+		l.histogramsMutex.Lock()
+		l.workersAbandoned = true
+		l.histogramsMutex.Unlock()
+	}
+}
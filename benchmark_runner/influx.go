@@ -0,0 +1,105 @@
+package benchmark_runner
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const influxMeasurement = "ftsb"
+
+// influxWriter writes the metrics of each reporting period to a file ( or named pipe / socket file ) using
+// the InfluxDB line protocol, so that they can be ingested by InfluxDB/Telegraf without any custom parsing
+type influxWriter struct {
+	mutex  sync.Mutex
+	file   *os.File
+	w      *bufio.Writer
+	tags   string
+	closed bool
+}
+
+// influxEscape escapes the characters with special meaning on line protocol tag keys, tag values and field keys
+func influxEscape(s string) string {
+	return strings.NewReplacer(",", "\\,", "=", "\\=", " ", "\\ ").Replace(s)
+}
+
+// parseInfluxTags parses a comma separated list of key=value tags into the line protocol tag set
+func parseInfluxTags(tagsStr string, workers uint) (tags string, err error) {
+	tagsMap := map[string]string{"workers": fmt.Sprintf("%d", workers)}
+	for _, kv := range strings.Split(tagsStr, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			err = fmt.Errorf("malformed influx tag %s. Expected key=value", kv)
+			return
+		}
+		tagsMap[parts[0]] = parts[1]
+	}
+	keys := make([]string, 0, len(tagsMap))
+	for k := range tagsMap {
+		keys = append(keys, k)
+	}
+	// tags should be sorted by key for best performance on the InfluxDB side
+	sort.Strings(keys)
+	for _, k := range keys {
+		tags += fmt.Sprintf(",%s=%s", influxEscape(k), influxEscape(tagsMap[k]))
+	}
+	return
+}
+
+func newInfluxWriter(fileName string, tagsStr string, workers uint) (*influxWriter, error) {
+	tags, err := parseInfluxTags(tagsStr, workers)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Create(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return &influxWriter{file: file, w: bufio.NewWriter(file), tags: tags}, nil
+}
+
+// write writes one line with the provided fields, flushing it right away so that it can be consumed while the
+// benchmark is still running
+func (i *influxWriter) write(now time.Time, fields map[string]float64) error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	if i.closed {
+		return nil
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fieldsStr := make([]string, 0, len(keys))
+	for _, k := range keys {
+		fieldsStr = append(fieldsStr, fmt.Sprintf("%s=%s", influxEscape(k), strconv.FormatFloat(wrapNaN(fields[k]), 'f', -1, 64)))
+	}
+	_, err := fmt.Fprintf(i.w, "%s%s %s %d\n", influxMeasurement, i.tags, strings.Join(fieldsStr, ","), now.UnixNano())
+	if err != nil {
+		return err
+	}
+	return i.w.Flush()
+}
+
+func (i *influxWriter) close() error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	if i.closed {
+		return nil
+	}
+	i.closed = true
+	if err := i.w.Flush(); err != nil {
+		return err
+	}
+	return i.file.Close()
+}
@@ -0,0 +1,91 @@
+package benchmark_runner
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// expandInputFileNames expands a comma separated list of file names and/or glob patterns
+// into the ordered list of files to read from. Glob matches are sorted lexically, so that
+// shards named data.0, data.1, ... are read in order.
+func expandInputFileNames(fileNames string) (files []string, err error) {
+	for _, pattern := range strings.Split(fileNames, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		var matches []string
+		matches, err = filepath.Glob(pattern)
+		if err != nil {
+			return
+		}
+		if len(matches) == 0 {
+			// not a pattern (or no match). Keep it so that opening it reports the proper error
+			matches = []string{pattern}
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+	return
+}
+
+// lastByteReader keeps track of the last byte read from the wrapped reader
+type lastByteReader struct {
+	r    io.Reader
+	last byte
+}
+
+func (l *lastByteReader) Read(p []byte) (int, error) {
+	c, err := l.r.Read(p)
+	if c > 0 {
+		l.last = p[c-1]
+	}
+	return c, err
+}
+
+// missingNewlineReader emits a single newline if the previous (non empty) reader did not end with one,
+// so that the last row of a file is not merged with the first row of the next one when concatenating them
+type missingNewlineReader struct {
+	prev *lastByteReader
+	done bool
+}
+
+func (m *missingNewlineReader) Read(p []byte) (int, error) {
+	if m.done || len(p) == 0 || m.prev.last == 0 || m.prev.last == '\n' {
+		m.done = true
+		return 0, io.EOF
+	}
+	p[0] = '\n'
+	m.done = true
+	return 1, nil
+}
+
+// concatenateReaders returns a reader that is the logical concatenation of the provided readers,
+// treating them as one continuous stream of rows
+func concatenateReaders(readers []io.Reader) io.Reader {
+	all := make([]io.Reader, 0, 2*len(readers))
+	for _, r := range readers {
+		lb := &lastByteReader{r: r}
+		all = append(all, lb, &missingNewlineReader{prev: lb})
+	}
+	return io.MultiReader(all...)
+}
+
+// rowCountingReader counts the rows ( newlines ) read from the wrapped reader, enabling to report the progress of
+// inputs whose size is not known upfront, like STDIN
+type rowCountingReader struct {
+	r    io.Reader
+	rows *uint64
+}
+
+func (c *rowCountingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 {
+		atomic.AddUint64(c.rows, uint64(bytes.Count(p[:n], []byte{'\n'})))
+	}
+	return n, err
+}
@@ -0,0 +1,97 @@
+package benchmark_runner
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// parseCustomLabels parses the comma separated list of -custom-labels, rejecting the built-in ones
+func parseCustomLabels(labelsStr string) ([]string, error) {
+	labels := []string{}
+	seen := map[string]bool{}
+	for _, label := range strings.Split(labelsStr, ",") {
+		label = strings.TrimSpace(label)
+		if label == "" {
+			continue
+		}
+		if _, builtIn := labelClasses[label]; builtIn {
+			return nil, fmt.Errorf("-custom-labels %s is a built-in command type, and already has its own histograms", label)
+		}
+		if !seen[label] {
+			seen[label] = true
+			labels = append(labels, label)
+		}
+	}
+	return labels, nil
+}
+
+// initCustomLabels creates the histograms and counters of each custom label
+func (l *BenchmarkRunner) initCustomLabels() {
+	l.customHistograms = make(map[string]*hdrhistogram.Histogram)
+	for _, label := range l.customLabels {
+		l.customHistograms[label] = hdrhistogram.New(1, 1000000, 3)
+		l.histogramOverflows[label] = new(uint64)
+		l.classBytes[label] = &classBytesCounter{}
+	}
+}
+
+// recordCustomLabel accounts for a command whose command type is not one of the built-in ones, on its own
+// histogram when registered via -custom-labels. Otherwise the command is not accounted on any class, so it is
+// tallied as unrecognized and a warning is logged the first time the command type is seen.
+// Must be called while holding histogramsMutex
+func (l *BenchmarkRunner) recordCustomLabel(label string, cmdStat CmdStat, sampled bool) {
+	hist, registered := l.customHistograms[label]
+	if !registered {
+		if l.unrecognizedLabels[label] == 0 {
+			log.Printf("WARNING: unrecognized command type %s. Its commands are not accounted on any class histogram. Use one of the built-in command types, or register it via -custom-labels\n", label)
+		}
+		l.unrecognizedLabels[label]++
+		return
+	}
+	l.countBytes(label, cmdStat)
+	l.countCommand(label)
+	if sampled {
+		l.countOverflow(label, hist.RecordValue(int64(cmdStat.Latency())))
+	}
+}
+
+// GetCustomLabelsCountsMap returns the number of commands of each custom label
+func (b *BenchmarkRunner) GetCustomLabelsCountsMap() map[string]interface{} {
+	configs := map[string]interface{}{}
+	for _, label := range b.customLabels {
+		configs[label] = b.commandCounts[label]
+	}
+	return configs
+}
+
+// GetUnrecognizedLabelsMap returns the number of commands of each unrecognized command type
+func (b *BenchmarkRunner) GetUnrecognizedLabelsMap() map[string]interface{} {
+	configs := map[string]interface{}{}
+	for label, count := range b.unrecognizedLabels {
+		configs[label] = count
+	}
+	return configs
+}
+
+// printLabelsSummary prints the rate and latency of each custom label, and warns about the unrecognized ones
+func (l *BenchmarkRunner) printLabelsSummary(took float64) {
+	for _, label := range l.customLabels {
+		fmt.Printf("\t- %s %0.0f ops/sec\t\tq50 lat %0.3f ms\n", label, float64(l.commandCounts[label])/took,
+			float64(l.customHistograms[label].ValueAtQuantile(50.0))/10e2)
+	}
+	if len(l.unrecognizedLabels) == 0 {
+		return
+	}
+	labels := make([]string, 0, len(l.unrecognizedLabels))
+	for label := range l.unrecognizedLabels {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		fmt.Printf("\tWARNING: %d commands with the unrecognized command type %s were not accounted on any class histogram\n", l.unrecognizedLabels[label], label)
+	}
+}
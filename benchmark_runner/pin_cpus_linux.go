@@ -0,0 +1,21 @@
+package benchmark_runner
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// pinThreadToCPU sets the affinity of the calling OS thread to the given CPU, so that it does not migrate.
+// The calling goroutine must be locked to its OS thread
+func pinThreadToCPU(cpu int) error {
+	var mask [1024 / 64]uint64
+	if cpu >= len(mask)*64 {
+		return syscall.EINVAL
+	}
+	mask[cpu/64] |= 1 << (uint(cpu) % 64)
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, uintptr(len(mask)*8), uintptr(unsafe.Pointer(&mask[0])))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
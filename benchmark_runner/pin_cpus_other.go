@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package benchmark_runner
+
+// pinThreadToCPU is a no-op on platforms without thread affinity support, where -pin-cpus only locks
+// each worker goroutine to its OS thread
+func pinThreadToCPU(cpu int) error {
+	return nil
+}
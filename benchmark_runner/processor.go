@@ -16,3 +16,11 @@ type ProcessorCloser interface {
 	// Close cleans up after a Processor
 	Close(doLoad bool)
 }
+
+// ProcessorFlusher is a Processor that may hold commands across batches ( e.g. on a pipeline spanning them ), whose
+// stats are returned by the following batches
+type ProcessorFlusher interface {
+	Processor
+	// Flush issues the commands still held once the worker has no more batches, returning their stats
+	Flush(doLoad bool) Stat
+}
@@ -0,0 +1,147 @@
+package benchmark_runner
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// reportSocketBacklog is the number of reporting periods buffered per subscriber. Once a subscriber falls this
+	// far behind, the newer periods are dropped for it, so that a slow subscriber never blocks the benchmark
+	reportSocketBacklog = 16
+	// reportSocketWriteTimeout bounds each write to a subscriber, so that a stalled one is disconnected
+	reportSocketWriteTimeout = 5 * time.Second
+)
+
+// reportSocket streams the metrics of each reporting period as JSON lines to every subscriber connected to a TCP
+// or Unix socket ( like a live dashboard ), without touching the disk
+type reportSocket struct {
+	listener    net.Listener
+	mutex       sync.Mutex
+	subscribers map[*reportSubscriber]struct{}
+	wg          sync.WaitGroup
+	dropped     uint64
+	closed      bool
+}
+
+type reportSubscriber struct {
+	conn  net.Conn
+	lines chan []byte
+}
+
+// reportLine is the JSON line sent to the subscribers on each reporting period
+type reportLine struct {
+	TimestampMs int64              `json:"TimestampMs"`
+	Workers     uint               `json:"Workers"`
+	Metrics     map[string]float64 `json:"Metrics"`
+}
+
+// parseReportSocketAddress returns the network and address to listen on. Addresses prefixed by unix: are Unix
+// socket paths, while the others are TCP [host]:port addresses
+func parseReportSocketAddress(address string) (network string, addr string) {
+	if strings.HasPrefix(address, "unix:") {
+		return "unix", strings.TrimPrefix(address, "unix:")
+	}
+	return "tcp", address
+}
+
+func newReportSocket(address string) (*reportSocket, error) {
+	listener, err := net.Listen(parseReportSocketAddress(address))
+	if err != nil {
+		return nil, err
+	}
+	s := &reportSocket{listener: listener, subscribers: map[*reportSubscriber]struct{}{}}
+	go s.accept()
+	return s, nil
+}
+
+// accept registers every new connection as a subscriber, until the socket is closed
+func (s *reportSocket) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		sub := &reportSubscriber{conn: conn, lines: make(chan []byte, reportSocketBacklog)}
+		s.mutex.Lock()
+		if s.closed {
+			s.mutex.Unlock()
+			conn.Close()
+			return
+		}
+		s.subscribers[sub] = struct{}{}
+		s.wg.Add(1)
+		s.mutex.Unlock()
+		go s.send(sub)
+	}
+}
+
+// send writes the lines of a subscriber to its connection, disconnecting it on the first failed write
+func (s *reportSocket) send(sub *reportSubscriber) {
+	defer s.wg.Done()
+	defer sub.conn.Close()
+	for line := range sub.lines {
+		sub.conn.SetWriteDeadline(time.Now().Add(reportSocketWriteTimeout))
+		if _, err := sub.conn.Write(line); err != nil {
+			s.mutex.Lock()
+			delete(s.subscribers, sub)
+			s.mutex.Unlock()
+			return
+		}
+	}
+}
+
+// publish sends the metrics of a reporting period to every subscriber. The line is dropped ( and accounted for )
+// for the subscribers that are too far behind, and when there are no subscribers at all
+func (s *reportSocket) publish(now time.Time, workers uint, fields map[string]float64) {
+	metrics := make(map[string]float64, len(fields))
+	for k, v := range fields {
+		metrics[k] = wrapNaN(v)
+	}
+	line, err := json.Marshal(reportLine{TimestampMs: now.UnixNano() / int64(time.Millisecond), Workers: workers, Metrics: metrics})
+	if err != nil {
+		log.Printf("error while encoding the report socket line: %v\n", err)
+		return
+	}
+	line = append(line, '\n')
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return
+	}
+	if len(s.subscribers) == 0 {
+		atomic.AddUint64(&s.dropped, 1)
+		return
+	}
+	for sub := range s.subscribers {
+		select {
+		case sub.lines <- line:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	}
+}
+
+// close stops accepting subscribers and waits for the buffered lines to be sent to the connected ones, returning
+// the number of lines dropped during the benchmark
+func (s *reportSocket) close() uint64 {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return atomic.LoadUint64(&s.dropped)
+	}
+	s.closed = true
+	s.listener.Close()
+	for sub := range s.subscribers {
+		close(sub.lines)
+	}
+	s.subscribers = nil
+	s.mutex.Unlock()
+	s.wg.Wait()
+	return atomic.LoadUint64(&s.dropped)
+}
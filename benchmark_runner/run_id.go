@@ -0,0 +1,59 @@
+package benchmark_runner
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// runTags holds the -tag key=value pairs of a run, recorded on the results so that they can be indexed and
+// queried alongside the results of other runs
+type runTags map[string]string
+
+// String returns the tags as a comma separated list of key=value pairs, sorted by key, as accepted back by Set
+func (t runTags) String() string {
+	keys := make([]string, 0, len(t))
+	for k := range t {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", k, t[k]))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set adds the key=value pair ( or comma separated list of pairs ) of a -tag occurrence. A repeated key keeps the
+// last value
+func (t runTags) Set(value string) error {
+	for _, kv := range strings.Split(value, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return fmt.Errorf("malformed tag %s. Expected key=value", kv)
+		}
+		t[parts[0]] = parts[1]
+	}
+	return nil
+}
+
+// newRunID returns a random ( version 4 ) UUID identifying a run
+func newRunID() (string, error) {
+	uuid := make([]byte, 16)
+	if _, err := rand.Read(uuid); err != nil {
+		return "", err
+	}
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16]), nil
+}
+
+// RunID returns the unique ID of the run, generated once per invocation and recorded on the results
+func (l *BenchmarkRunner) RunID() string {
+	return l.runID
+}
@@ -31,8 +31,9 @@ func sendOrQueueBatch(ch *duplexChannel, count *int, batch Batch, unsent []Batch
 	if len(unsent) == 0 && len(ch.toWorker) < cap(ch.toWorker) {
 		ch.sendToWorker(batch)
 	} else {
-		return append(unsent, batch)
+		unsent = append(unsent, batch)
 	}
+	ch.recordQueueDepth(len(ch.toWorker) + len(unsent))
 	return unsent
 }
 
@@ -86,8 +87,9 @@ type DocDecoder interface {
 // ScanWithIndexer reads databuild from the provided bufio.Reader br until a limit is reached (if -1, all items are read).
 // Data is decoded by DocDecoder decoder and then placed into appropriate batches, using the supplied DocIndexer,
 // which are then dispatched to workers (duplexChannel chosen by DocIndexer). Scan does flow control to make sure workers are not left idle for too long
-// and also that the scanning process  does not starve them of CPU.
-func scanWithIndexer(channels []*duplexChannel, batchSize uint, limit uint64, br *bufio.Reader, decoder DocDecoder, factory BatchFactory, indexer DocIndexer) uint64 {
+// and also that the scanning process  does not starve them of CPU. If stop is not nil, scanning also stops once it returns true.
+// Scanning blocks while maxInflight batches are outstanding (if 0, 3 times the capacity of the channels).
+func scanWithIndexer(channels []*duplexChannel, batchSize uint, limit uint64, maxInflight int, br *bufio.Reader, decoder DocDecoder, factory BatchFactory, indexer DocIndexer, stop func() bool) uint64 {
 	var itemsRead uint64
 	numChannels := len(channels)
 
@@ -134,6 +136,9 @@ func scanWithIndexer(channels []*duplexChannel, batchSize uint, limit uint64, br
 	// so we don't go over a limit (olimit), in order to slow down the scanner so it doesn't starve the workers
 	ocnt := 0
 	olimit := numChannels * cap(channels[0].toWorker) * 3
+	if maxInflight > 0 {
+		olimit = maxInflight
+	}
 	for {
 
 		// Check whether incoming items limit reached.
@@ -141,6 +146,9 @@ func scanWithIndexer(channels []*duplexChannel, batchSize uint, limit uint64, br
 		if limit > 0 && itemsRead == limit {
 			break
 		}
+		if stop != nil && stop() {
+			break
+		}
 
 		caseLimit := len(cases)
 		if ocnt >= olimit {
@@ -0,0 +1,55 @@
+package benchmark_runner
+
+import (
+	"fmt"
+	"log"
+	"sort"
+)
+
+// captureServerInfo snapshots the target database metrics, if the benchmark supports it.
+// Failing to capture them is not fatal, given they are a side channel of the results
+func captureServerInfo(b Benchmark) map[string]float64 {
+	capturer, ok := b.(ServerInfoCapturer)
+	if !ok {
+		return map[string]float64{}
+	}
+	snapshot, err := capturer.CaptureServerInfo()
+	if err != nil {
+		log.Printf("cannot capture the server info: %v\n", err)
+		return map[string]float64{}
+	}
+	return snapshot
+}
+
+// getServerInfoMap returns the metrics before and after the benchmark, and the deltas of the metrics present on both
+func getServerInfoMap(before, after map[string]float64) map[string]interface{} {
+	configs := map[string]interface{}{}
+	if len(before) == 0 && len(after) == 0 {
+		return configs
+	}
+	delta := map[string]float64{}
+	for metric, afterV := range after {
+		if beforeV, exists := before[metric]; exists {
+			delta[metric] = afterV - beforeV
+		}
+	}
+	configs["Before"] = before
+	configs["After"] = after
+	configs["Delta"] = delta
+	return configs
+}
+
+func printServerInfo(serverInfo map[string]interface{}) {
+	before := serverInfo["Before"].(map[string]float64)
+	after := serverInfo["After"].(map[string]float64)
+	delta := serverInfo["Delta"].(map[string]float64)
+	metrics := make([]string, 0, len(delta))
+	for metric := range delta {
+		metrics = append(metrics, metric)
+	}
+	sort.Strings(metrics)
+	fmt.Printf("\tServer info (before -> after):\n")
+	for _, metric := range metrics {
+		fmt.Printf("\t- %s %.2f -> %.2f (%+.2f)\n", metric, before[metric], after[metric], delta[metric])
+	}
+}
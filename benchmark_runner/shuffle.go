@@ -0,0 +1,76 @@
+package benchmark_runner
+
+import (
+	"bufio"
+	"io"
+	"math/rand"
+)
+
+// shuffleReader is an io.Reader that emits the rows (newline terminated lines) of the wrapped reader
+// in a random order. Rows are kept in a bounded window: once the window is full, each new row
+// replaces a randomly picked one which is emitted. A window of 0 buffers the entire input,
+// producing a full shuffle at the expense of memory.
+type shuffleReader struct {
+	br      *bufio.Reader
+	rng     *rand.Rand
+	window  uint64
+	rows    [][]byte
+	pending []byte
+	eof     bool
+	err     error
+}
+
+func newShuffleReader(r io.Reader, window uint64, seed int64) *shuffleReader {
+	return &shuffleReader{
+		br:     bufio.NewReaderSize(r, defaultReadSize),
+		rng:    rand.New(rand.NewSource(seed)),
+		window: window,
+		rows:   make([][]byte, 0),
+	}
+}
+
+// fill reads rows from the wrapped reader until the window is full or the input is exhausted
+func (s *shuffleReader) fill() {
+	for !s.eof && (s.window == 0 || uint64(len(s.rows)) < s.window) {
+		row, err := s.br.ReadBytes('\n')
+		if len(row) > 0 {
+			if row[len(row)-1] != '\n' {
+				row = append(row, '\n')
+			}
+			s.rows = append(s.rows, row)
+		}
+		if err != nil {
+			s.eof = true
+			if err != io.EOF {
+				s.err = err
+			}
+		}
+	}
+}
+
+// next removes a random row from the window
+func (s *shuffleReader) next() []byte {
+	pos := s.rng.Intn(len(s.rows))
+	row := s.rows[pos]
+	last := len(s.rows) - 1
+	s.rows[pos] = s.rows[last]
+	s.rows[last] = nil
+	s.rows = s.rows[:last]
+	return row
+}
+
+func (s *shuffleReader) Read(p []byte) (int, error) {
+	if len(s.pending) == 0 {
+		s.fill()
+		if len(s.rows) == 0 {
+			if s.err != nil {
+				return 0, s.err
+			}
+			return 0, io.EOF
+		}
+		s.pending = s.next()
+	}
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
@@ -0,0 +1,40 @@
+package benchmark_runner
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	hdrhistogram "github.com/HdrHistogram/hdrhistogram-go"
+)
+
+// printSnapshot prints the overall counts and latency quantiles of each class up until now, without stopping
+// the benchmark. The histograms are copied while holding histogramsMutex and the quantiles computed afterwards,
+// so that the workers are blocked for as little as possible
+func (l *BenchmarkRunner) printSnapshot(out io.Writer) {
+	classes := [][2]string{{"allCommands", "Total"}, {"setupWrite", "Setup Writes"}, {"write", "Writes"}, {"update", "Updates"}, {"read", "Reads"}, {"readCursor", "Cursor Reads"}, {"delete", "Deletes"}}
+	l.histogramsMutex.Lock()
+	histograms := map[string]*hdrhistogram.Histogram{"allCommands": l.totalHistogram, "setupWrite": l.setupWriteHistogram, "write": l.writeHistogram, "update": l.updateHistogram, "read": l.readHistogram, "readCursor": l.readCursorHistogram, "delete": l.deleteHistogram}
+	counts := map[string]int64{}
+	snapshots := map[string]*hdrhistogram.Histogram{}
+	for _, class := range classes {
+		counts[class[0]] = l.commandCounts[class[0]]
+		if counts[class[0]] > 0 {
+			snapshots[class[0]] = hdrhistogram.Import(histograms[class[0]].Export())
+		}
+	}
+	l.histogramsMutex.Unlock()
+
+	took := time.Since(l.start)
+	fmt.Fprintf(out, "\nSnapshot after %0.3fsec:\n", took.Seconds())
+	for _, class := range classes {
+		hist, exists := snapshots[class[0]]
+		if !exists {
+			continue
+		}
+		_, quantiles := generateQuantileMap(hist)
+		fmt.Fprintf(out, "\t- %s %d commands, %0.0f ops/sec\tq50 lat %0.3f ms, q95 lat %0.3f ms, q99 lat %0.3f ms, q999 lat %0.3f ms, q100 lat %0.3f ms\n",
+			class[1], counts[class[0]], calculateRateMetrics(counts[class[0]], 0, took),
+			quantiles["q50"], quantiles["q95"], quantiles["q99"], quantiles["q999"], quantiles["q100"])
+	}
+}
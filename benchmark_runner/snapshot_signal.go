@@ -0,0 +1,28 @@
+//go:build !windows
+// +build !windows
+
+package benchmark_runner
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// handleSnapshotSignal prints a snapshot of the overall counts and quantiles to stderr whenever the process
+// receives a SIGUSR1, until done is closed
+func (l *BenchmarkRunner) handleSnapshotSignal(done chan struct{}) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-signals:
+				l.printSnapshot(os.Stderr)
+			case <-done:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,5 @@
+package benchmark_runner
+
+// handleSnapshotSignal is a no-op on windows, given there is no SIGUSR1
+func (l *BenchmarkRunner) handleSnapshotSignal(done chan struct{}) {
+}
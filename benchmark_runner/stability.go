@@ -0,0 +1,108 @@
+package benchmark_runner
+
+import (
+	"math"
+)
+
+// rateStability holds the spread of the per reporting period rates of a class
+type rateStability struct {
+	intervals int
+	mean      float64
+	stdDev    float64
+	min       float64
+	max       float64
+}
+
+// coefficientOfVariation returns the standard deviation relative to the mean rate, enabling to compare the
+// stability of runs with different throughputs
+func (s rateStability) coefficientOfVariation() float64 {
+	if s.mean == 0 {
+		return 0
+	}
+	return s.stdDev / s.mean
+}
+
+func (s rateStability) toMap() map[string]interface{} {
+	return map[string]interface{}{
+		"Intervals":              s.intervals,
+		"MeanRate":               s.mean,
+		"StdDevRate":             s.stdDev,
+		"CoefficientOfVariation": s.coefficientOfVariation(),
+		"MinRate":                s.min,
+		"MaxRate":                s.max,
+	}
+}
+
+// computeRateStability computes the mean, population standard deviation and range of the given rates
+func computeRateStability(rates []float64) (s rateStability) {
+	s.intervals = len(rates)
+	if s.intervals == 0 {
+		return
+	}
+	s.min = math.Inf(1)
+	s.max = math.Inf(-1)
+	sum := 0.0
+	for _, rate := range rates {
+		sum += rate
+		s.min = math.Min(s.min, rate)
+		s.max = math.Max(s.max, rate)
+	}
+	s.mean = sum / float64(s.intervals)
+	sumSquares := 0.0
+	for _, rate := range rates {
+		sumSquares += (rate - s.mean) * (rate - s.mean)
+	}
+	s.stdDev = math.Sqrt(sumSquares / float64(s.intervals))
+	return
+}
+
+// intervalRates returns the rates of the reporting periods of a time series. The last reporting period is cut short
+// by the end of the benchmark, so it is not taken into account when there are other periods
+func intervalRates(ts []DataPoint) []float64 {
+	if len(ts) > 1 {
+		ts = ts[:len(ts)-1]
+	}
+	rates := make([]float64, 0, len(ts))
+	for _, dp := range ts {
+		rates = append(rates, dp.MultiValues["rate"])
+	}
+	return rates
+}
+
+// GetThroughputStabilityMap returns the standard deviation and coefficient of variation of the per reporting period
+// ops/sec, overall and per class. A high coefficient of variation signals an unstable throughput ( like GC pauses,
+// thermal throttling or server side compactions ) that is hidden by the averages.
+// Must be called after GetTimeSeriesMap, given it relies on the time series being sorted
+func (b *BenchmarkRunner) GetThroughputStabilityMap() map[string]interface{} {
+	configs := map[string]interface{}{}
+	classes := map[string][]DataPoint{
+		"setupWrite": b.setupWriteTs,
+		"write":      b.writeTs,
+		"update":     b.updateTs,
+		"read":       b.readTs,
+		"readCursor": b.readCursorTs,
+		"delete":     b.deleteTs,
+	}
+	var totalRates []float64
+	for class, ts := range classes {
+		if b.commandCounts[class] == 0 {
+			continue
+		}
+		rates := intervalRates(ts)
+		if len(rates) == 0 {
+			continue
+		}
+		configs[class] = computeRateStability(rates).toMap()
+		// every class time series gets a datapoint on each reporting period
+		if totalRates == nil {
+			totalRates = make([]float64, len(rates))
+		}
+		for i := 0; i < len(rates) && i < len(totalRates); i++ {
+			totalRates[i] += rates[i]
+		}
+	}
+	if totalRates != nil {
+		configs["allCommands"] = computeRateStability(totalRates).toMap()
+	}
+	return configs
+}
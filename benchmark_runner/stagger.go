@@ -0,0 +1,48 @@
+package benchmark_runner
+
+import (
+	"time"
+)
+
+// staggerWorkers launches every worker but the first one, each -worker-stagger after the previous one was ready,
+// recording when each of them was ready to issue commands ( relative to the benchmark start ). Once stop is closed
+// ( given the input was exhausted ) the remaining workers are no longer launched, and skip is called for each of
+// them instead. The returned channel is closed once every worker was either launched or skipped
+func (l *BenchmarkRunner) staggerWorkers(launch func(workerNum int), skip func(), stop <-chan struct{}) chan struct{} {
+	done := make(chan struct{})
+	l.workerStarts = []time.Duration{0}
+	go func() {
+		defer close(done)
+		for i := 1; i < int(l.workers); i++ {
+			select {
+			case <-stop:
+				skip()
+				continue
+			case <-time.After(l.workerStagger):
+			}
+			l.initWg.Add(1)
+			launch(i)
+			l.initWg.Wait()
+			l.workerStarts = append(l.workerStarts, time.Since(l.start))
+		}
+	}()
+	return done
+}
+
+// GetWorkerStaggerMap returns the offset from the benchmark start at which each worker was ready, and the whole
+// ramp-up duration. It returns an empty map if the workers were not staggered
+func (b *BenchmarkRunner) GetWorkerStaggerMap() map[string]interface{} {
+	configs := map[string]interface{}{}
+	if b.workerStagger <= 0 || len(b.workerStarts) == 0 {
+		return configs
+	}
+	offsets := make([]float64, 0, len(b.workerStarts))
+	for _, offset := range b.workerStarts {
+		offsets = append(offsets, float64(offset.Microseconds())/1000.0)
+	}
+	configs["StaggerMillis"] = float64(b.workerStagger.Microseconds()) / 1000.0
+	configs["RampUpMillis"] = offsets[len(offsets)-1]
+	configs["StartedWorkers"] = len(offsets)
+	configs["WorkerStartOffsetsMillis"] = offsets
+	return configs
+}
@@ -24,6 +24,12 @@ type CmdStat struct {
 	timedOut      bool
 	rx            uint64 // bytes received
 	tx            uint64 // bytes received
+	// microseconds until the first byte of the reply was received ( 0 when not measured by the processor )
+	firstByteLatency uint64
+	// the query was cut off by a server side timeout, replying with partial results or an error
+	queryTimedOut bool
+	// start timestamp in microseconds since epoch ( 0 when not provided by the processor )
+	startUnixMicros uint64
 }
 
 func (c *CmdStat) StartTs() uint64 {
@@ -34,6 +40,14 @@ func (c *CmdStat) SetStartTs(startTs uint64) {
 	c.startTs = startTs
 }
 
+func (c *CmdStat) StartUnixMicros() uint64 {
+	return c.startUnixMicros
+}
+
+func (c *CmdStat) SetStartUnixMicros(startUnixMicros uint64) {
+	c.startUnixMicros = startUnixMicros
+}
+
 func (c *CmdStat) Tx() uint64 {
 	return c.tx
 }
@@ -58,6 +72,22 @@ func (c *CmdStat) SetLatency(latency uint64) {
 	c.latency = latency
 }
 
+func (c *CmdStat) FirstByteLatency() uint64 {
+	return c.firstByteLatency
+}
+
+func (c *CmdStat) SetFirstByteLatency(firstByteLatency uint64) {
+	c.firstByteLatency = firstByteLatency
+}
+
+func (c *CmdStat) IsQueryTimedOut() bool {
+	return c.queryTimedOut
+}
+
+func (c *CmdStat) SetQueryTimedOut(queryTimedOut bool) {
+	c.queryTimedOut = queryTimedOut
+}
+
 func (c *CmdStat) Label() []byte {
 	return c.cmdQueryGroup
 }
@@ -70,6 +100,14 @@ func (c *CmdStat) CmdQueryId() []byte {
 	return c.cmdQueryId
 }
 
+func (c *CmdStat) IsError() bool {
+	return c.error
+}
+
+func (c *CmdStat) IsTimedOut() bool {
+	return c.timedOut
+}
+
 func NewCmdStat(cmdGroup []byte, cmdQueryId []byte, latency uint64, error bool, timedOut bool, rx uint64, tx uint64) *CmdStat {
 	return &CmdStat{cmdQueryGroup: cmdGroup, cmdQueryId: cmdQueryId, latency: latency, error: error, timedOut: timedOut, rx: rx, tx: tx}
 }
@@ -83,7 +121,7 @@ func NewStat() *Stat {
 
 func (s *Stat) AddEntry(cmdGroup []byte, cmdQueryId []byte, startTs, latencyUs uint64, error bool, timedOut bool, rx, tx uint64) *Stat {
 	s.totalCmds++
-	entry := CmdStat{cmdGroup, cmdQueryId, startTs, latencyUs, error, timedOut, rx, tx}
+	entry := CmdStat{cmdQueryGroup: cmdGroup, cmdQueryId: cmdQueryId, startTs: startTs, latency: latencyUs, error: error, timedOut: timedOut, rx: rx, tx: tx}
 	s.cmdStats = append(s.cmdStats, entry)
 	return s
 }
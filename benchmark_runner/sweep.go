@@ -0,0 +1,118 @@
+package benchmark_runner
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// ConcurrencySweepResult holds the results of replaying the workload at each of the -concurrency-sweep worker counts
+type ConcurrencySweepResult struct {
+	Metadata            string `json:"Metadata"`
+	ResultFormatVersion string `json:"ResultFormatVersion"`
+	ResultType          string `json:"ResultType"`
+
+	// Unique ID of the sweep, shared by all of its levels, and its -tag key=value pairs
+	RunID string            `json:"RunID"`
+	Tags  map[string]string `json:"Tags"`
+
+	// Worker counts of the sweep, in the order they were run
+	ConcurrencyLevels []uint `json:"ConcurrencyLevels"`
+
+	// Results of each level, in the same order as ConcurrencyLevels
+	Levels []TestResult `json:"Levels"`
+
+	// Command, duration and error of each of the teardown commands issued after the last level
+	Teardown []map[string]interface{} `json:"Teardown"`
+}
+
+// parseConcurrencySweep returns the worker counts of -concurrency-sweep
+func (l *BenchmarkRunner) parseConcurrencySweep() ([]uint, error) {
+	if l.fileName == "" {
+		return nil, fmt.Errorf("-concurrency-sweep requires -input, given the workload is read once per level")
+	}
+	if l.influxOutFile != "" || l.traceFile != "" || l.hdrOutFile != "" {
+		return nil, fmt.Errorf("-concurrency-sweep can't be used alongside -influx-out-file, -trace-file or -hdr-out-file, given each level would overwrite them")
+	}
+	levels := []uint{}
+	for _, level := range strings.Split(l.concurrencySweep, ",") {
+		workers, err := strconv.ParseUint(strings.TrimSpace(level), 10, 32)
+		if err != nil || workers == 0 {
+			return nil, fmt.Errorf("-concurrency-sweep expects a comma separated list of positive worker counts. Got %s", l.concurrencySweep)
+		}
+		levels = append(levels, uint(workers))
+	}
+	return levels, nil
+}
+
+// runConcurrencySweep replays the whole input workload once per worker count, resetting the histograms and
+// re-reading the input between levels. The setup and teardown commands are issued only once, around the sweep
+func (l *BenchmarkRunner) runConcurrencySweep(b Benchmark, workQueues uint, levels []uint) {
+	l.sweepResults = make([]TestResult, 0, len(levels))
+	for i, workers := range levels {
+		if i > 0 {
+			l.resetRunState()
+			if resetter, ok := b.(RunStateResetter); ok {
+				resetter.ResetRunState()
+			}
+			if err := l.closeInput(); err != nil {
+				log.Printf("error while closing the input: %v\n", err)
+			}
+			l.GetBufferedReader()
+			if l.arrivals != nil {
+				l.arrivals = newPoissonArrivals(float64(l.maxRPS), l.arrivalSeed)
+			}
+		}
+		l.workers = workers
+		fmt.Printf("\nConcurrency sweep level %d/%d: %d workers\n", i+1, len(levels), workers)
+		l.runWorkload(b, workQueues)
+		l.collectResults(b)
+		l.summary()
+		l.sweepResults = append(l.sweepResults, l.testResult)
+		if l.HeartbeatAborted() {
+			// the remaining levels can't be run against an unreachable target database
+			break
+		}
+	}
+}
+
+// sweepSummary prints how the throughput and latency scale across the -concurrency-sweep levels, relative to the
+// first level, and writes the results of every level to -json-out-file
+func (l *BenchmarkRunner) sweepSummary(teardown []map[string]interface{}) {
+	result := ConcurrencySweepResult{
+		Metadata:            l.Metadata,
+		ResultFormatVersion: CurrentResultFormatVersion,
+		ResultType:          ConcurrencySweepResultType,
+		RunID:               l.runID,
+		Tags:                l.runTags,
+		ConcurrencyLevels:   make([]uint, 0, len(l.sweepResults)),
+		Levels:              l.sweepResults,
+		Teardown:            teardown,
+	}
+	fmt.Printf("\nConcurrency sweep summary:\n")
+	fmt.Printf("\t%10s %15s %10s %12s %15s %15s\n", "workers", "ops/sec", "speedup", "efficiency", "q50 lat (ms)", "q99 lat (ms)")
+	baseRate, baseWorkers := 0.0, uint(0)
+	for i, level := range l.sweepResults {
+		result.ConcurrencyLevels = append(result.ConcurrencyLevels, level.Workers)
+		rate := level.OverallRates["overallOpsRate"].(float64)
+		if i == 0 {
+			baseRate, baseWorkers = rate, level.Workers
+		}
+		speedup, efficiency := 0.0, 0.0
+		if baseRate > 0 {
+			speedup = rate / baseRate
+			efficiency = speedup * float64(baseWorkers) / float64(level.Workers)
+		}
+		quantiles := level.OverallQuantiles["allCommands"].(map[string]float64)
+		fmt.Printf("\t%10d %15.0f %9.2fx %11.1f%% %15.3f %15.3f\n", level.Workers, rate, speedup, efficiency*100.0, quantiles["q50"], quantiles["q99"])
+	}
+	for _, timing := range teardown {
+		if timing["Error"] != "" {
+			fmt.Printf("\tTeardown: %s failed after %0.3f ms: %s\n", timing["Command"], timing["DurationMillis"], timing["Error"])
+			continue
+		}
+		fmt.Printf("\tTeardown: %s took %0.3f ms\n", timing["Command"], timing["DurationMillis"])
+	}
+	l.writeJsonOutFile(result)
+}
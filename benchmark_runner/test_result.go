@@ -3,6 +3,15 @@ package benchmark_runner
 type DataPoint struct {
 	Timestamp   int64              `json:"Timestamp"`
 	MultiValues map[string]float64 `json:"MultiValues"`
+	// latency histogram buckets of the reporting period, when requested via -time-series-buckets
+	Buckets []HistogramBucket `json:"Buckets,omitempty"`
+}
+
+// HistogramBucket is a non empty latency histogram bucket, holding the number of commands whose latency is up to
+// ValueMs ( and above the previous bucket value )
+type HistogramBucket struct {
+	ValueMs float64 `json:"ValueMs"`
+	Count   int64   `json:"Count"`
 }
 
 func (p DataPoint) AddValue(s string, value float64) {
@@ -26,17 +35,36 @@ type TestResult struct {
 	// Test Configs
 	Metadata            string `json:"Metadata"`
 	ResultFormatVersion string `json:"ResultFormatVersion"`
+	ResultType          string `json:"ResultType"`
 	Limit               uint64 `json:"Limit"`
 	Workers             uint   `json:"Workers"`
 	MaxRps              uint64 `json:"MaxRps"`
 
+	// Unique ID of the run, and its -tag key=value pairs
+	RunID string            `json:"RunID"`
+	Tags  map[string]string `json:"Tags"`
+
+	// Effective random seed and benchmark tool version, enabling to reproduce the run
+	Seed    int64  `json:"Seed"`
+	Version string `json:"Version"`
+
 	// DB Spefic Configs
 	DBSpecificConfigs map[string]interface{} `json:"DBSpecificConfigs"`
 
+	// Effective value of all flags, enabling to reproduce the run
+	RunConfig map[string]interface{} `json:"RunConfig"`
+
 	StartTime      int64 `json:"StartTime"`
 	EndTime        int64 `json:"EndTime"`
 	DurationMillis int64 `json:"DurationMillis"`
 
+	// Condition that stopped the benchmark before exhausting the input ( like a transfer budget ), if any
+	StopReason string `json:"StopReason"`
+
+	// Window whose error rate exceeded -max-error-rate ( time span, commands, errors and error rate ), when it
+	// stopped the benchmark
+	ErrorRateStop map[string]interface{} `json:"ErrorRateStop"`
+
 	// Totals
 	Totals map[string]interface{} `json:"Totals"`
 
@@ -45,11 +73,59 @@ type TestResult struct {
 	// Overall Rates
 	OverallRates map[string]interface{} `json:"OverallRates"`
 
+	// Spread of the per reporting period rates, overall and per class
+	ThroughputStability map[string]interface{} `json:"ThroughputStability"`
+
 	// Overall Quantiles
 	OverallQuantiles map[string]interface{} `json:"OverallQuantiles"`
 
+	// Overall time to the first reply byte quantiles, when measured by the processor
+	OverallFirstByteQuantiles map[string]interface{} `json:"OverallFirstByteQuantiles"`
+
+	// Latencies above the histograms highest trackable value, per class
+	HistogramOverflows map[string]interface{} `json:"HistogramOverflows"`
+
+	// Commands cut off by a server side query timeout, per query group
+	QueryTimeouts map[string]interface{} `json:"QueryTimeouts"`
+
+	// Transmitted and received bytes, per class
+	ClassBytes map[string]interface{} `json:"ClassBytes"`
+
 	// Time-Series
 	TimeSeries map[string]interface{} `json:"TimeSeries"`
 
+	// Workers queue depth (backpressure)
+	QueueDepth map[string]interface{} `json:"QueueDepth"`
+
+	// Time each worker started at, when staggered
+	WorkerStagger map[string]interface{} `json:"WorkerStagger"`
+
+	// Benchmark client memory and GC stats
+	ClientStats map[string]interface{} `json:"ClientStats"`
+
+	// Target database metrics before and after the benchmark, and their deltas
+	ServerInfo map[string]interface{} `json:"ServerInfo"`
+
+	// Index memory per indexed document, once the benchmark is done
+	IndexMemory map[string]interface{} `json:"IndexMemory"`
+
+	// Commands and bytes sent to each cluster node
+	ClusterDistribution map[string]interface{} `json:"ClusterDistribution"`
+
+	// Raw and gzip compressed size of a sample of the transmitted commands
+	Compression map[string]interface{} `json:"Compression"`
+
+	// Connections re-established during the benchmark, overall and per worker
+	Reconnects map[string]interface{} `json:"Reconnects"`
+
+	// Command, duration and error of each of the teardown commands ( like dropping the index ) issued after the benchmark
+	Teardown []map[string]interface{} `json:"Teardown"`
+
+	// Expected results verification tally
+	Verification map[string]interface{} `json:"Verification"`
+
+	// Loaded documents against the increase of the documents reported by the target database
+	CountVerification map[string]interface{} `json:"CountVerification"`
+
 	PerSecondEncodedHistograms map[uint64]string `json:"PerSecondEncodedHistograms"`
 }
@@ -0,0 +1,60 @@
+package benchmark_runner
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+)
+
+// traceBufferSize is the write buffer size of each trace file shard, so that tracing does not become the bottleneck
+const traceBufferSize = 1024 * 1024
+
+// traceHeader describes the columns of each trace file record
+const traceHeader = "timestamp_us,cmdType,cmdQueryId,latency_us,tx_bytes,rx_bytes,error\n"
+
+// traceWriter writes the outcome of every command issued by a worker as an individual record, enabling offline
+// analysis beyond what the histograms capture. Each worker writes to its own shard, avoiding any contention
+type traceWriter struct {
+	file *os.File
+	w    *bufio.Writer
+}
+
+// traceShardName returns the name of the trace file shard of a worker
+func traceShardName(fileName string, workerNum int) string {
+	return fmt.Sprintf("%s.%d", fileName, workerNum)
+}
+
+func newTraceWriter(fileName string, workerNum int) (*traceWriter, error) {
+	file, err := os.Create(traceShardName(fileName, workerNum))
+	if err != nil {
+		return nil, err
+	}
+	t := &traceWriter{file: file, w: bufio.NewWriterSize(file, traceBufferSize)}
+	if _, err = t.w.WriteString(traceHeader); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *traceWriter) write(cmdStat CmdStat) error {
+	timestamp := cmdStat.StartUnixMicros()
+	if timestamp == 0 {
+		// the processor only provided the start second
+		timestamp = cmdStat.StartTs() * 1000000
+	}
+	errorFlag := 0
+	if cmdStat.IsError() {
+		errorFlag = 1
+	}
+	_, err := fmt.Fprintf(t.w, "%d,%s,%s,%d,%d,%d,%d\n", timestamp, cmdStat.Label(), cmdStat.CmdQueryId(), cmdStat.Latency(), cmdStat.Tx(), cmdStat.Rx(), errorFlag)
+	return err
+}
+
+func (t *traceWriter) close() error {
+	if err := t.w.Flush(); err != nil {
+		t.file.Close()
+		return err
+	}
+	return t.file.Close()
+}
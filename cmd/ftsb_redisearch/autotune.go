@@ -0,0 +1,86 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// autotuneDepths are the pipeline depths swept by -autotune-pipeline, in order
+var autotuneDepths = []int{1, 4, 16, 64, 256}
+
+// pipelineTuner sweeps the pipeline depths at the start of the benchmark, each during -autotune-step, measuring the
+// throughput of each one, and settles on the depth with the highest throughput for the remainder of the benchmark
+type pipelineTuner struct {
+	mutex     sync.Mutex
+	start     time.Time
+	completed []uint64
+	// the chosen depth. 0 while sweeping
+	chosen int
+}
+
+var tuner = &pipelineTuner{completed: make([]uint64, len(autotuneDepths))}
+
+// reset restarts the sweep, between the levels of a -concurrency-sweep, given the best depth depends on the
+// number of workers
+func (t *pipelineTuner) reset() {
+	t.mutex.Lock()
+	t.start = time.Time{}
+	t.completed = make([]uint64, len(autotuneDepths))
+	t.chosen = 0
+	t.mutex.Unlock()
+}
+
+// stage returns the index of the depth being swept, or -1 once the sweep is over.
+// Must be called while holding the mutex
+func (t *pipelineTuner) stage(now time.Time) int {
+	if t.start.IsZero() {
+		t.start = now
+	}
+	stage := int(now.Sub(t.start) / autotuneStep)
+	if stage >= len(autotuneDepths) {
+		return -1
+	}
+	return stage
+}
+
+// depth returns the pipeline depth to use for the next commands
+func (t *pipelineTuner) depth() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.chosen > 0 {
+		return t.chosen
+	}
+	if stage := t.stage(time.Now()); stage >= 0 {
+		return autotuneDepths[stage]
+	}
+	best := 0
+	for stage, completed := range t.completed {
+		log.Printf("autotune pipeline: depth %d achieved %.0f ops/sec\n", autotuneDepths[stage], float64(completed)/autotuneStep.Seconds())
+		if completed > t.completed[best] {
+			best = stage
+		}
+	}
+	t.chosen = autotuneDepths[best]
+	log.Printf("autotune pipeline: using a pipeline depth of %d for the remainder of the benchmark\n", t.chosen)
+	return t.chosen
+}
+
+// record accounts for completed commands on the depth being swept
+func (t *pipelineTuner) record(commands int) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.chosen > 0 {
+		return
+	}
+	if stage := t.stage(time.Now()); stage >= 0 {
+		t.completed[stage] += uint64(commands)
+	}
+}
+
+// chosenDepth returns the depth chosen by the sweep, or 0 if the sweep did not complete
+func (t *pipelineTuner) chosenDepth() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.chosen
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	radix "github.com/mediocregopher/radix/v3"
+	"github.com/mediocregopher/radix/v3/resp/resp2"
+	"log"
+	"strings"
+	"time"
+)
+
+// isClusterNode probes the server with CLUSTER INFO. Servers without cluster support reply with an error,
+// while cluster nodes reply with the cluster state
+func isClusterNode() (clusterNode bool, err error) {
+	conn, err := radix.Dial("tcp", host, getDialOpts(time.Second*10)...)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	var info string
+	err = conn.Do(radix.Cmd(&info, "CLUSTER", "INFO"))
+	var respErr resp2.Error
+	if errors.As(err, &respErr) {
+		return false, nil
+	}
+	if err != nil {
+		return
+	}
+	return strings.Contains(info, "cluster_state:"), nil
+}
+
+// detectClusterMode enables -cluster-mode when the target is a cluster node, given pointing the benchmark at a
+// cluster node without it would fail with MOVED redirects
+func detectClusterMode() {
+	if clusterMode {
+		return
+	}
+	if clusterNode, err := isClusterNode(); err == nil && clusterNode {
+		log.Printf("%s is a cluster node. Enabling -cluster-mode\n", host)
+		clusterMode = true
+	}
+}
+
+// checkRedirect returns an actionable error when a command was redirected ( MOVED/ASK ) while not on
+// -cluster-mode, given that means the target is a cluster node and most commands would fail
+func checkRedirect(err error) error {
+	if clusterMode || err == nil {
+		return nil
+	}
+	var respErr resp2.Error
+	if !errors.As(err, &respErr) {
+		return nil
+	}
+	msg := respErr.Error()
+	if strings.HasPrefix(msg, "MOVED ") || strings.HasPrefix(msg, "ASK ") {
+		return fmt.Errorf("received a redirect (%s) from %s, which is a cluster node. Run the benchmark with -cluster-mode", msg, host)
+	}
+	return nil
+}
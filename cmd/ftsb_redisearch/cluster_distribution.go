@@ -0,0 +1,44 @@
+package main
+
+import (
+	"sync"
+)
+
+// clusterDistribution keeps track of the commands and transmitted bytes sent to each cluster node,
+// surfacing uneven key distributions ( hot slots )
+type clusterDistribution struct {
+	mutex    sync.Mutex
+	commands map[string]uint64
+	txBytes  map[string]uint64
+}
+
+var nodesDistribution = &clusterDistribution{
+	commands: map[string]uint64{},
+	txBytes:  map[string]uint64{},
+}
+
+// reset clears the distribution, between the levels of a -concurrency-sweep
+func (c *clusterDistribution) reset() {
+	c.mutex.Lock()
+	c.commands = map[string]uint64{}
+	c.txBytes = map[string]uint64{}
+	c.mutex.Unlock()
+}
+
+func (c *clusterDistribution) record(addr string, txBytesCount uint64) {
+	c.mutex.Lock()
+	c.commands[addr]++
+	c.txBytes[addr] += txBytesCount
+	c.mutex.Unlock()
+}
+
+// GetClusterDistributionMap returns, per cluster node, the issued commands and transmitted bytes
+func (b *benchmark) GetClusterDistributionMap() map[string]interface{} {
+	configs := map[string]interface{}{}
+	nodesDistribution.mutex.Lock()
+	defer nodesDistribution.mutex.Unlock()
+	for addr, commands := range nodesDistribution.commands {
+		configs[addr] = map[string]interface{}{"Commands": commands, "TxBytes": nodesDistribution.txBytes[addr]}
+	}
+	return configs
+}
@@ -2,44 +2,95 @@ package main
 
 import (
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"github.com/RediSearch/ftsb/benchmark_runner"
 	radix "github.com/mediocregopher/radix/v3"
+	"github.com/mediocregopher/radix/v3/resp/resp2"
 	"golang.org/x/time/rate"
 	"log"
 	"math/rand"
+	"net"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// acrossBatchesRowsBuffer is the number of rows handed over to the connection processor ahead of being issued, with
+// -pipeline-across-batches. It matches the batch size, so that the worker can take the following batch as soon as the
+// rows of the current one were handed over
+const acrossBatchesRowsBuffer = 100
+
+// acrossBatchesStatsBuffer is the number of replied commands whose stats are buffered until the following batch of
+// the worker, with -pipeline-across-batches. Once full, the connection processor waits for it to issue more commands
+const acrossBatchesStatsBuffer = 10000
+
 type processor struct {
 	rows           chan string
 	cmdChan        chan benchmark_runner.Stat
 	wg             *sync.WaitGroup
-	vanillaClient  *radix.Pool
+	vanillaClient  radix.Client
 	vanillaCluster *radix.Cluster
 	clusterTopo    radix.ClusterTopo
+	// number of commands to accumulate before flushing the pipeline
+	pipelineThreshold int
 }
 
-func (p *processor) Init(workerNumber int, _ bool, totalWorkers int) {
-	var err error = nil
+// pendingCmd holds the details of a command queued on a pipeline, required to account for it once the pipeline is flushed
+type pendingCmd struct {
+	cmdType      string
+	cmdQueryId   string
+	cmd          string
+	key          string
+	start        time.Time
+	txBytesCount uint64
+	reply        *cmdReply
+}
+
+// getDialOpts returns the connection options shared by every connection to the Redis server(s)
+func getDialOpts(timeout time.Duration) []radix.DialOpt {
 	opts := make([]radix.DialOpt, 0)
 	if password != "" {
 		opts = append(opts, radix.DialAuthPass(password))
 	}
-	opts = append(opts, radix.DialTimeout(time.Second*600))
+	opts = append(opts, radix.DialTimeout(timeout))
+	return opts
+}
+
+func (p *processor) Init(workerNumber int, _ bool, totalWorkers int) {
+	var err error = nil
+	p.pipelineThreshold = nextPipelineThreshold()
+	opts := getDialOpts(time.Second * 600)
+	if cmdTimeout > 0 {
+		opts = append(opts, radix.DialReadTimeout(cmdTimeout), radix.DialWriteTimeout(cmdTimeout))
+	}
 
+	// connections dialed once the processor is initialized are reconnections ( e.g. after a timeout or a dropped connection )
+	var initialized int32
+	defer atomic.StoreInt32(&initialized, 1)
 	customConnFunc := func(network, addr string) (radix.Conn, error) {
+		if atomic.LoadInt32(&initialized) == 1 {
+			reconnects.record(workerNumber)
+		}
 		return radix.Dial(network, addr, opts...,
 		)
 	}
 
+	poolOpts := []radix.PoolOpt{radix.PoolConnFunc(customConnFunc), radix.PoolPipelineWindow(0, 0)}
+	if cmdTimeout > 0 {
+		// a timed out connection is discarded. Replace it right away instead of waiting for the default 1 second
+		poolOpts = append(poolOpts, radix.PoolOnEmptyCreateAfter(0))
+	}
+
 	// this cluster will use the ClientFunc to create a pool to each node in the
 	// cluster.
 	poolFunc := func(network, addr string) (radix.Client, error) {
-		return radix.NewPool(network, addr, int(1), radix.PoolConnFunc(customConnFunc), radix.PoolPipelineWindow(0, 0))
+		if noPool {
+			return &dialPerCmdClient{network: network, addr: addr, opts: opts}, nil
+		}
+		return radix.NewPool(network, addr, int(1), poolOpts...)
 	}
 
 	if clusterMode {
@@ -54,34 +105,51 @@ func (p *processor) Init(workerNumber int, _ bool, totalWorkers int) {
 			log.Fatalf("Error retrieving cluster topology. error = %v", err)
 		}
 		p.clusterTopo = p.vanillaCluster.Topo()
+		if prewarmConns {
+			for _, node := range p.clusterTopo.Primaries() {
+				client, err := p.vanillaCluster.Client(node.Addr)
+				if err == nil {
+					err = client.Do(radix.Cmd(nil, "PING"))
+				}
+				if err != nil {
+					log.Fatalf("Error while prewarming the connection to %s. error = %v", node.Addr, err)
+				}
+			}
+		}
+	} else if noPool {
+		p.vanillaClient = &dialPerCmdClient{network: "tcp", addr: host, opts: opts}
 	} else {
 		// add randomness on ping interval
 		//pingInterval := (20+rand.Intn(10))*1000000000
 		// We dont want PING to be issed from 5 to 5 seconds given that we know the connection is alive on the benchmark
-		p.vanillaClient, err = radix.NewPool("tcp", host, 1, radix.PoolConnFunc(customConnFunc), radix.PoolPipelineWindow(0, 0), radix.PoolPingInterval(1*time.Hour))
+		p.vanillaClient, err = radix.NewPool("tcp", host, 1, append(poolOpts, radix.PoolPingInterval(1*time.Hour))...)
 		if err != nil {
 			log.Fatalf("Error preparing for redisearch ingestion, while creating new pool. error = %v", err)
 		}
+		if prewarmConns {
+			if err = p.vanillaClient.Do(radix.Cmd(nil, "PING")); err != nil {
+				log.Fatalf("Error while prewarming the connection to %s. error = %v", host, err)
+			}
+		}
 	}
 }
 
 func connectionProcessor(p *processor, rateLimiter *rate.Limiter, useRateLimiter bool) {
 	cmdSlots := make([][]radix.CmdAction, 0, 0)
-	timesSlots := make([][]time.Time, 0, 0)
-	replies := make([]interface{}, 0, 0)
+	pendingSlots := make([][]pendingCmd, 0, 0)
 	clusterSlots := make([][2]uint16, 0, 0)
 	clusterAddr := make([]string, 0, 0)
 	clusterAddrLen := 0
 	slotP := 0
 	if !clusterMode {
 		cmdSlots = append(cmdSlots, make([]radix.CmdAction, 0, 0))
-		timesSlots = append(timesSlots, make([]time.Time, 0, 0))
+		pendingSlots = append(pendingSlots, make([]pendingCmd, 0, 0))
 	} else {
 		for _, ClusterNode := range p.clusterTopo {
 			for _, slot := range ClusterNode.Slots {
 				clusterSlots = append(clusterSlots, slot)
 				cmdSlots = append(cmdSlots, make([]radix.CmdAction, 0, 0))
-				timesSlots = append(timesSlots, make([]time.Time, 0, 0))
+				pendingSlots = append(pendingSlots, make([]pendingCmd, 0, 0))
 				clusterAddr = append(clusterAddr, ClusterNode.Addr)
 			}
 		}
@@ -89,9 +157,73 @@ func connectionProcessor(p *processor, rateLimiter *rate.Limiter, useRateLimiter
 		// start at a random slot between 0 and clusterAddrLen
 		slotP = rand.Intn(clusterAddrLen)
 	}
+	// flushOlderThan issues the commands queued on every pipeline whose oldest command was queued at least maxAge ago
+	flushOlderThan := func(maxAge time.Duration) {
+		for slot := range cmdSlots {
+			if len(cmdSlots[slot]) == 0 || time.Since(pendingSlots[slot][0].start) < maxAge {
+				continue
+			}
+			if !clusterMode {
+				cmdSlots[slot], pendingSlots[slot] = flushCmds(p, p.vanillaClient, cmdSlots[slot], pendingSlots[slot])
+			} else {
+				client, _ := p.vanillaCluster.Client(clusterAddr[slot])
+				cmdSlots[slot], pendingSlots[slot] = flushCmds(p, client, cmdSlots[slot], pendingSlots[slot])
+			}
+		}
+	}
+	// flushAll issues the commands queued on every pipeline
+	flushAll := func() {
+		flushOlderThan(0)
+	}
 
-	for row := range p.rows {
+	// with -pipeline-across-batches the pipelines are also flushed once their oldest command waited for
+	// -pipeline-flush-interval, given the rows of the following batch may take a while to arrive
+	var flushTicks <-chan time.Time
+	if pipelineAcrossBatches {
+		ticker := time.NewTicker(pipelineFlushInterval / 2)
+		defer ticker.Stop()
+		flushTicks = ticker.C
+	}
+
+rows:
+	for {
+		var row string
+		select {
+		case <-flushTicks:
+			flushOlderThan(pipelineFlushInterval)
+			continue
+		case next, open := <-p.rows:
+			if !open {
+				break rows
+			}
+			row = next
+		}
 		cmdType, cmdQueryId, keyPos, cmd, key, clusterSlot, docFields, bytelen, _ := preProcessCmd(row)
+		cmdQueryId, exp, dependent, err := parseQueryId(cmdQueryId)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if dependent && thinkTime > 0 {
+			// the operation it depends on has to complete before the think time starts
+			flushAll()
+			time.Sleep(thinkTime)
+		}
+
+		if noIndex && storesOnIndex(cmd) {
+			log.Fatalf("-no-index can't load %s commands, given they store the documents on the index itself. Generate the input with HSET instead", cmd)
+		}
+		if skipNoIndexCmd(cmd) {
+			continue
+		}
+		if isQueryCmd(cmd) {
+			// malformed PARAMS would otherwise only be reported as errors on each reply
+			if _, _, err := queryParams(docFields); err != nil {
+				log.Fatal(err)
+			}
+			if queryTimeoutMs > 0 && !hasOption(docFields, "TIMEOUT") {
+				docFields = append(docFields, "TIMEOUT", strconv.Itoa(queryTimeoutMs))
+			}
+		}
 
 		if clusterSlot > -1 {
 			for i, sArr := range clusterSlots {
@@ -110,81 +242,132 @@ func connectionProcessor(p *processor, rateLimiter *rate.Limiter, useRateLimiter
 		if debug > 2 {
 			fmt.Println(keyPos, slotP, key, clusterSlot, cmd, strings.Join(docFields, ","), clusterSlots)
 		}
-		if useRateLimiter {
+		if classLimiter := loader.ClassRateLimiter(cmdType); classLimiter != nil {
+			r := classLimiter.ReserveN(time.Now(), int(1))
+			time.Sleep(r.Delay())
+		} else if useRateLimiter {
 			r := rateLimiter.ReserveN(time.Now(), int(1))
 			time.Sleep(r.Delay())
 		}
+		txCompression.maybeRecord(cmd, docFields)
+		start := time.Now()
+		if arrival, scheduled := loader.NextArrival(); scheduled {
+			time.Sleep(time.Until(arrival))
+			start = arrival
+		}
 		if !clusterMode {
-			cmdSlots[slotP], timesSlots[slotP] = sendFlatCmd(p, p.vanillaClient, cmdType, cmdQueryId, cmd, docFields, bytelen, cmdSlots[slotP], replies, timesSlots[slotP])
+			cmdSlots[slotP], pendingSlots[slotP] = sendFlatCmd(p, p.vanillaClient, cmdType, cmdQueryId, exp, cmd, key, docFields, bytelen, start, cmdSlots[slotP], pendingSlots[slotP])
 		} else {
 			client, _ := p.vanillaCluster.Client(clusterAddr[slotP])
-			cmdSlots[slotP], timesSlots[slotP] = sendFlatCmd(p, client, cmdType, cmdQueryId, cmd, docFields, bytelen, cmdSlots[slotP], replies, timesSlots[slotP])
+			nodesDistribution.record(clusterAddr[slotP], bytelen)
+			cmdSlots[slotP], pendingSlots[slotP] = sendFlatCmd(p, client, cmdType, cmdQueryId, exp, cmd, key, docFields, bytelen, start, cmdSlots[slotP], pendingSlots[slotP])
 		}
 	}
+	// flush the commands still queued on the pipelines, so that none is left behind at the end of the batch ( or of
+	// the whole input, with -pipeline-across-batches )
+	flushAll()
 	p.wg.Done()
 }
 
-func getRxLen(v interface{}) (res uint64) {
-	res = 0
-	switch x := v.(type) {
-	case []string:
-		for _, i := range x {
-			res += uint64(len(i))
-		}
-	case string:
-		res += uint64(len(x))
-	default:
-		res = 0
+// nextPipelineThreshold returns the number of commands to accumulate before flushing the pipeline.
+// When -pipeline-jitter is set, the threshold is randomly varied by up to that number of commands,
+// so that the workers do not flush in synchronized bursts
+func nextPipelineThreshold() int {
+	threshold := pipeline
+	if autotunePipe {
+		threshold = tuner.depth()
 	}
-	return
+	if pipelineJitter > 0 {
+		threshold += rand.Intn(2*pipelineJitter+1) - pipelineJitter
+	}
+	if threshold < 1 {
+		threshold = 1
+	}
+	return threshold
 }
 
-func sendFlatCmd(p *processor, client radix.Client, cmdType, cmdQueryId, cmd string, docfields []string, txBytesCount uint64, cmds []radix.CmdAction, replies []interface{}, times []time.Time) ([]radix.CmdAction, []time.Time) {
-	var err error = nil
-	var rcv interface{}
-	rxBytesCount := uint64(0)
+func sendFlatCmd(p *processor, client radix.Client, cmdType, cmdQueryId string, exp *expectation, cmd string, key string, docfields []string, txBytesCount uint64, start time.Time, cmds []radix.CmdAction, pending []pendingCmd) ([]radix.CmdAction, []pendingCmd) {
+	threshold := p.pipelineThreshold
+	if setupPipeline > 0 {
+		if isSetupWrite(cmdType) {
+			threshold = setupPipeline
+		}
+		// the setup writes and the other commands are not pipelined together, so that the measured commands
+		// latency does not include the setup writes of a deeper pipeline
+		if len(pending) > 0 && isSetupWrite(pending[len(pending)-1].cmdType) != isSetupWrite(cmdType) {
+			cmds, pending = flushCmds(p, client, cmds, pending)
+		}
+	}
+	rcv := &cmdReply{queryId: cmdQueryId, exp: exp}
 	var radixFlatCmd = radix.Cmd(rcv, cmd, docfields...)
 	cmds = append(cmds, radixFlatCmd)
-	replies = append(replies, rcv)
-	start := time.Now()
-	times = append(times, start)
-	cmds, times = sendIfRequired(p, client, cmdType, cmdQueryId, cmds, err, times, rxBytesCount, replies, txBytesCount)
-	return cmds, times
+	pending = append(pending, pendingCmd{cmdType: cmdType, cmdQueryId: cmdQueryId, cmd: cmd, key: key, start: start, txBytesCount: txBytesCount, reply: rcv})
+	if len(cmds) >= threshold {
+		cmds, pending = flushCmds(p, client, cmds, pending)
+	}
+	return cmds, pending
+}
+
+// isSetupWrite returns true for the commands of the index population phase, pipelined with -setup-pipeline
+func isSetupWrite(cmdType string) bool {
+	return cmdType == "SETUP_WRITE"
 }
 
-func sendIfRequired(p *processor, client radix.Client, cmdType string, cmdQueryId string, cmds []radix.CmdAction, err error, times []time.Time, rxBytesCount uint64, replies []interface{}, txBytesCount uint64) ([]radix.CmdAction, []time.Time) {
-	cmdLen := len(cmds)
-	if cmdLen >= pipeline {
-		if cmdLen == 1 {
-			// if pipeline is 1 no need to pipeline
-			err = client.Do(cmds[0])
+// flushCmds issues the queued commands and accounts for each of them
+func flushCmds(p *processor, client radix.Client, cmds []radix.CmdAction, pending []pendingCmd) ([]radix.CmdAction, []pendingCmd) {
+	var err error = nil
+	if len(cmds) == 1 {
+		// if pipeline is 1 no need to pipeline
+		err = client.Do(cmds[0])
+	} else {
+		err = client.Do(radix.Pipeline(cmds...))
+	}
+	endT := time.Now()
+	// error replies only affect their own command, while any other error ( like a timeout ) affects the whole pipeline
+	connErr := false
+	timedOut := false
+	if redirectErr := checkRedirect(err); redirectErr != nil {
+		log.Fatal(redirectErr)
+	}
+	if err != nil {
+		var netErr net.Error
+		var respErr resp2.Error
+		connErr = !errors.As(err, &respErr)
+		timedOut = errors.As(err, &netErr) && netErr.Timeout()
+		// once the benchmark is aborted by the heartbeat, the commands still in flight are expected to fail
+		if continueOnErr || loader.HeartbeatAborted() {
+			if debug > 0 {
+				log.Println(fmt.Sprintf("Received an error with the following command(s): %v, error: %v", cmds, err))
+			}
 		} else {
-			err = client.Do(radix.Pipeline(cmds...))
+			log.Fatal(err)
 		}
-		endT := time.Now()
-		if err != nil {
-			if continueOnErr {
-				if debug > 0 {
-					log.Println(fmt.Sprintf("Received an error with the following command(s): %v, error: %v", cmds, err))
-				}
-			} else {
-				log.Fatal(err)
-			}
+	}
+	for _, c := range pending {
+		cmdErr := connErr || c.reply.err != nil
+		if !cmdErr {
+			loadedDocs.record(c.cmd, c.key)
 		}
-		for pos, t := range times {
-			duration := endT.Sub(t)
-			took := uint64(duration.Microseconds())
-			rcv := replies[pos]
-			rxBytesCount += getRxLen(rcv)
-			stat := benchmark_runner.NewStat().AddEntry([]byte(cmdType), []byte(cmdQueryId), uint64(t.Unix()), took, false, false, txBytesCount, rxBytesCount)
-			p.cmdChan <- *stat
+		duration := endT.Sub(c.start)
+		took := uint64(duration.Microseconds())
+		stat := benchmark_runner.NewStat().AddEntry([]byte(c.cmdType), []byte(c.cmdQueryId), uint64(c.start.Unix()), took, cmdErr, timedOut, c.reply.rxBytesCount, c.txBytesCount)
+		cmdStat := &stat.CmdStats()[0]
+		if !c.reply.firstByteAt.IsZero() {
+			firstByte := uint64(c.reply.firstByteAt.Sub(c.start).Microseconds())
+			if firstByte < 1 {
+				firstByte = 1
+			}
+			cmdStat.SetFirstByteLatency(firstByte)
 		}
-		cmds = nil
-		cmds = make([]radix.CmdAction, 0, 0)
-		times = nil
-		times = make([]time.Time, 0, 0)
+		cmdStat.SetQueryTimedOut(c.reply.queryTimedOut)
+		cmdStat.SetStartUnixMicros(uint64(c.start.UnixNano() / 1000))
+		p.cmdChan <- *stat
+	}
+	if autotunePipe {
+		tuner.record(len(pending))
 	}
-	return cmds, times
+	p.pipelineThreshold = nextPipelineThreshold()
+	return make([]radix.CmdAction, 0, 0), make([]pendingCmd, 0, 0)
 }
 
 // ProcessBatch reads eventsBatches which contain rows of databuild for FT.ADD redis command string
@@ -192,14 +375,33 @@ func (p *processor) ProcessBatch(b benchmark_runner.Batch, doLoad bool, rateLimi
 	outstat = *benchmark_runner.NewStat()
 	events := b.(*eventsBatch)
 	rowCnt := uint64(len(events.rows))
-	if doLoad {
-		buflen := rowCnt + 1
-
-		p.cmdChan = make(chan benchmark_runner.Stat, buflen)
-		p.wg = &sync.WaitGroup{}
-		p.rows = make(chan string, buflen)
-		p.wg.Add(1)
-		go connectionProcessor(p, rateLimiter, useRateLimiter)
+	if doLoad && pipelineAcrossBatches {
+		if p.rows == nil {
+			// a single connection processor, and its pipelines, spans every batch of the worker
+			p.startConnectionProcessor(acrossBatchesRowsBuffer, acrossBatchesStatsBuffer, rateLimiter, useRateLimiter)
+		}
+		for _, row := range events.rows {
+			for queued := false; !queued; {
+				select {
+				case p.rows <- row:
+					queued = true
+				case cmdStat := <-p.cmdChan:
+					outstat.Merge(cmdStat)
+				}
+			}
+		}
+		// the commands still on the pipelines are accounted by the following batches, or once flushed
+		for drained := false; !drained; {
+			select {
+			case cmdStat := <-p.cmdChan:
+				outstat.Merge(cmdStat)
+			default:
+				drained = true
+			}
+		}
+	} else if doLoad {
+		buflen := int(rowCnt + 1)
+		p.startConnectionProcessor(buflen, buflen, rateLimiter, useRateLimiter)
 		for _, row := range events.rows {
 			p.rows <- row
 		}
@@ -217,6 +419,32 @@ func (p *processor) ProcessBatch(b benchmark_runner.Batch, doLoad bool, rateLimi
 	return
 }
 
+// startConnectionProcessor launches the connection processor issuing the rows sent to p.rows
+func (p *processor) startConnectionProcessor(rowsBuffer, statsBuffer int, rateLimiter *rate.Limiter, useRateLimiter bool) {
+	p.cmdChan = make(chan benchmark_runner.Stat, statsBuffer)
+	p.wg = &sync.WaitGroup{}
+	p.rows = make(chan string, rowsBuffer)
+	p.wg.Add(1)
+	go connectionProcessor(p, rateLimiter, useRateLimiter)
+}
+
+// Flush issues the commands still queued on the pipelines spanning batches, given -pipeline-across-batches
+func (p *processor) Flush(doLoad bool) (outstat benchmark_runner.Stat) {
+	outstat = *benchmark_runner.NewStat()
+	if !doLoad || !pipelineAcrossBatches || p.rows == nil {
+		return
+	}
+	close(p.rows)
+	go func() {
+		p.wg.Wait()
+		close(p.cmdChan)
+	}()
+	for cmdStat := range p.cmdChan {
+		outstat.Merge(cmdStat)
+	}
+	return
+}
+
 func (p *processor) Close(_ bool) {
 }
 
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RediSearch/ftsb/benchmark_runner"
+	"golang.org/x/time/rate"
+)
+
+// rttServer is a minimal RESP server replying +OK to every command, each reply sent once the round trip time
+// elapsed since the command arrived. Pipelined commands are replied back to back, like a server on a remote host
+type rttServer struct {
+	listener net.Listener
+	rtt      time.Duration
+	replied  uint64
+	wg       sync.WaitGroup
+	// number of commands received per key ( the first command argument )
+	keysMutex sync.Mutex
+	keys      map[string]int
+}
+
+func newRTTServer(tb testing.TB, rtt time.Duration) *rttServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatalf("cannot listen: %v", err)
+	}
+	s := &rttServer{listener: listener, rtt: rtt, keys: map[string]int{}}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.wg.Add(1)
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *rttServer) serve(conn net.Conn) {
+	defer s.wg.Done()
+	arrivals := make(chan time.Time, 100000)
+	go func() {
+		defer close(arrivals)
+		br := bufio.NewReader(conn)
+		for {
+			args, err := readCommand(br)
+			if err != nil {
+				return
+			}
+			if len(args) > 1 {
+				s.keysMutex.Lock()
+				s.keys[args[1]]++
+				s.keysMutex.Unlock()
+			}
+			arrivals <- time.Now()
+		}
+	}()
+	bw := bufio.NewWriter(conn)
+	for arrival := range arrivals {
+		time.Sleep(time.Until(arrival.Add(s.rtt)))
+		bw.WriteString("+OK\r\n")
+		atomic.AddUint64(&s.replied, 1)
+		if len(arrivals) == 0 {
+			if err := bw.Flush(); err != nil {
+				break
+			}
+		}
+	}
+	conn.Close()
+}
+
+// readCommand reads a command, sent as a RESP array of bulk strings
+func readCommand(br *bufio.Reader) ([]string, error) {
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	argc, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, 0, argc)
+	for ; argc > 0; argc-- {
+		if line, err = br.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arglen, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		arg := make([]byte, arglen)
+		if _, err = io.ReadFull(br, arg); err != nil {
+			return nil, err
+		}
+		if _, err = io.CopyN(ioutil.Discard, br, 2); err != nil {
+			return nil, err
+		}
+		args = append(args, string(arg))
+	}
+	return args, nil
+}
+
+// received returns the number of commands received for each key
+func (s *rttServer) received() map[string]int {
+	s.keysMutex.Lock()
+	defer s.keysMutex.Unlock()
+	keys := make(map[string]int, len(s.keys))
+	for key, count := range s.keys {
+		keys[key] = count
+	}
+	return keys
+}
+
+// Close stops accepting connections, and waits for the served ones to be closed by the client
+func (s *rttServer) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+// usePipeline points the processors to the server, with the given pipeline settings, returning a function that
+// restores the previous ones
+func usePipeline(s *rttServer, depth int, acrossBatches bool, flushInterval time.Duration) func() {
+	prevHost, prevPipeline, prevAcrossBatches, prevFlushInterval := host, pipeline, pipelineAcrossBatches, pipelineFlushInterval
+	host = s.listener.Addr().String()
+	pipeline = depth
+	pipelineAcrossBatches = acrossBatches
+	pipelineFlushInterval = flushInterval
+	return func() {
+		host, pipeline, pipelineAcrossBatches, pipelineFlushInterval = prevHost, prevPipeline, prevAcrossBatches, prevFlushInterval
+	}
+}
+
+// newBatch returns a batch of HSET rows over the documents from up until to, each with its own query id
+func newBatch(from, to int) *eventsBatch {
+	batch := ePool.Get().(*eventsBatch)
+	for n := from; n < to; n++ {
+		batch.rows = append(batch.rows, fmt.Sprintf("WRITE,W%d,1,HSET,doc%d,f,v", n, n))
+	}
+	return batch
+}
+
+// queryIds returns the query ids of the accounted commands, in the order they were accounted
+func queryIds(stat benchmark_runner.Stat) []string {
+	ids := []string{}
+	for _, cmdStat := range stat.CmdStats() {
+		ids = append(ids, string(cmdStat.CmdQueryId()))
+	}
+	return ids
+}
+
+func TestPipelineAcrossBatchesIssuesEveryRowOnce(t *testing.T) {
+	server := newRTTServer(t, time.Millisecond)
+	defer server.Close()
+	// only full pipelines are flushed ahead of Flush
+	defer usePipeline(server, 64, true, time.Hour)()
+
+	p := &processor{}
+	p.Init(0, true, 1)
+	defer p.vanillaClient.Close()
+	limiter := rate.NewLimiter(rate.Inf, 1)
+	accounted := []string{}
+	for from := 0; from < 250; from += 100 {
+		to := from + 100
+		if to > 250 {
+			to = 250
+		}
+		stat := p.ProcessBatch(newBatch(from, to), true, limiter, false)
+		accounted = append(accounted, queryIds(stat)...)
+	}
+	if len(accounted) > 192 {
+		t.Fatalf("expected at most the 3 full pipelines ( 192 commands ) to be accounted ahead of Flush, got %d", len(accounted))
+	}
+	tail := queryIds(p.Flush(true))
+	if len(tail) < 250-192 {
+		t.Errorf("expected Flush to account for at least the %d commands of the last pipeline, got %d", 250-192, len(tail))
+	}
+	accounted = append(accounted, tail...)
+
+	if len(accounted) != 250 {
+		t.Fatalf("expected 250 commands to be accounted, got %d", len(accounted))
+	}
+	for n, id := range accounted {
+		if id != fmt.Sprintf("W%d", n) {
+			t.Fatalf("expected the commands to be accounted in the input order, got %s at position %d", id, n)
+		}
+	}
+	received := server.received()
+	if len(received) != 250 {
+		t.Errorf("expected the 250 documents to be written, got %d", len(received))
+	}
+	for key, count := range received {
+		if count != 1 {
+			t.Errorf("expected %s to be written once, got %d", key, count)
+		}
+	}
+}
+
+func TestPipelineAcrossBatchesFlushesOnInterval(t *testing.T) {
+	server := newRTTServer(t, time.Millisecond)
+	defer server.Close()
+	defer usePipeline(server, 1000, true, 5*time.Millisecond)()
+
+	p := &processor{}
+	p.Init(0, true, 1)
+	defer p.vanillaClient.Close()
+	accounted := queryIds(p.ProcessBatch(newBatch(0, 10), true, rate.NewLimiter(rate.Inf, 1), false))
+	// the pipeline is far from full, so it is only issued once its oldest command waited for the flush interval
+	deadline := time.Now().Add(time.Second)
+	for len(server.received()) < 10 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if received := len(server.received()); received != 10 {
+		t.Fatalf("expected the 10 commands to be issued by the -pipeline-flush-interval ahead of Flush, got %d", received)
+	}
+	accounted = append(accounted, queryIds(p.Flush(true))...)
+	if len(accounted) != 10 {
+		t.Errorf("expected 10 commands to be accounted, got %d", len(accounted))
+	}
+}
+
+// benchmarkPipeline issues b.N FT.SEARCH commands, in batches of 100 rows, against a server 2ms away. Each
+// operation is a command
+func benchmarkPipeline(b *testing.B, acrossBatches bool) {
+	server := newRTTServer(b, 2*time.Millisecond)
+	defer server.Close()
+	defer usePipeline(server, 1000, acrossBatches, time.Millisecond)()
+
+	p := &processor{}
+	p.Init(0, true, 1)
+	defer p.vanillaClient.Close()
+	limiter := rate.NewLimiter(rate.Inf, 1)
+	b.ResetTimer()
+	start := time.Now()
+	for issued := 0; issued < b.N; {
+		batch := ePool.Get().(*eventsBatch)
+		for ; len(batch.rows) < 100 && issued < b.N; issued++ {
+			batch.rows = append(batch.rows, "READ,R1,1,FT.SEARCH,idx,hello")
+		}
+		p.ProcessBatch(batch, true, limiter, false)
+	}
+	p.Flush(true)
+	b.StopTimer()
+	b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "ops/sec")
+	if replied := atomic.LoadUint64(&server.replied); replied != uint64(b.N) {
+		b.Fatalf("expected %d commands to be replied, got %d", b.N, replied)
+	}
+}
+
+// BenchmarkPipelineAcrossBatches compares the pipelines flushed at the end of every batch, bounded to the batch
+// size despite -pipeline 1000, with the pipelines spanning batches of -pipeline-across-batches
+func BenchmarkPipelineAcrossBatches(b *testing.B) {
+	b.Run("per-batch", func(b *testing.B) {
+		benchmarkPipeline(b, false)
+	})
+	b.Run("across-batches", func(b *testing.B) {
+		benchmarkPipeline(b, true)
+	})
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/RediSearch/ftsb/benchmark_runner"
+	"os"
+)
+
+// compareCmd implements the compare subcommand, printing the percent change between two -json-out-file results.
+// It returns the process exit code: 1 when a regression exceeds the threshold, 2 on usage or read errors
+func compareCmd(args []string) int {
+	fs := flag.NewFlagSet("compare", flag.ContinueOnError)
+	threshold := fs.Float64("threshold", 5.0, "Percent change for the worse ( lower ops/sec or higher latency ) above which a metric is flagged as a regression.")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s compare [-threshold <percent>] <baseline.json> <comparison.json>\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+	baseline, err := benchmark_runner.ReadTestResult(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cannot read baseline results %s: %v\n", fs.Arg(0), err)
+		return 2
+	}
+	comparison, err := benchmark_runner.ReadTestResult(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cannot read comparison results %s: %v\n", fs.Arg(1), err)
+		return 2
+	}
+	regressions := benchmark_runner.CompareTestResults(baseline, comparison, *threshold, os.Stdout)
+	if regressions > 0 {
+		fmt.Printf("%d metric(s) regressed more than %.2f%%\n", regressions, *threshold)
+		return 1
+	}
+	return 0
+}
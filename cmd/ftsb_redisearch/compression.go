@@ -0,0 +1,92 @@
+package main
+
+import (
+	"compress/gzip"
+	"math/rand"
+	"strconv"
+	"sync"
+)
+
+// compressionSample estimates the compressibility of the transmitted command stream, by gzip compressing the RESP
+// encoding of a random sample of the commands ( as a single stream, so that the repetition across commands is
+// exploited as it would be by a compressed connection )
+type compressionSample struct {
+	mutex      sync.Mutex
+	commands   uint64
+	raw        uint64
+	compressed countingWriter
+	gz         *gzip.Writer
+}
+
+// countingWriter discards the written bytes, keeping track of how many there were
+type countingWriter struct {
+	n uint64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += uint64(len(p))
+	return len(p), nil
+}
+
+var txCompression = &compressionSample{}
+
+// encodeResp returns the RESP encoding of a command, as transmitted to the server
+func encodeResp(cmd string, args []string) []byte {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)+1), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range append([]string{cmd}, args...) {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	return buf
+}
+
+// reset discards the sample, between the levels of a -concurrency-sweep
+func (c *compressionSample) reset() {
+	c.mutex.Lock()
+	c.commands = 0
+	c.raw = 0
+	c.compressed = countingWriter{}
+	c.gz = nil
+	c.mutex.Unlock()
+}
+
+// maybeRecord adds the command to the sample with a probability of -compression-sample-rate
+func (c *compressionSample) maybeRecord(cmd string, args []string) {
+	if compressionSampleRate <= 0 || rand.Float64() >= compressionSampleRate {
+		return
+	}
+	encoded := encodeResp(cmd, args)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.gz == nil {
+		c.gz = gzip.NewWriter(&c.compressed)
+	}
+	c.gz.Write(encoded)
+	c.commands++
+	c.raw += uint64(len(encoded))
+}
+
+// GetCompressionMap returns the raw and gzip compressed size of the sampled commands so far, and their ratio. It
+// returns an empty map if no commands were sampled
+func (b *benchmark) GetCompressionMap() map[string]interface{} {
+	configs := map[string]interface{}{}
+	txCompression.mutex.Lock()
+	defer txCompression.mutex.Unlock()
+	if txCompression.gz == nil {
+		return configs
+	}
+	// flush the pending compressed bytes, while keeping the stream open given more commands may still be sampled
+	txCompression.gz.Flush()
+	configs["SampleRate"] = compressionSampleRate
+	configs["SampledCommands"] = txCompression.commands
+	configs["SampledTxBytes"] = txCompression.raw
+	configs["CompressedTxBytes"] = txCompression.compressed.n
+	configs["TxCompressionRatio"] = float64(txCompression.raw) / float64(txCompression.compressed.n)
+	return configs
+}
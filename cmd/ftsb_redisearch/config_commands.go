@@ -0,0 +1,36 @@
+package main
+
+import (
+	radix "github.com/mediocregopher/radix/v3"
+	"log"
+	"time"
+)
+
+// IssueCommand issues a single standalone command ( like the json config file setup and teardown commands )
+// on a dedicated connection to the Redis server
+func (b *benchmark) IssueCommand(args []string) (err error) {
+	if noIndex && requiresIndex(args[0]) {
+		log.Printf("Not issuing %s given -no-index\n", args[0])
+		return
+	}
+	conn, err := radix.Dial("tcp", host, getDialOpts(time.Second*600)...)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	err = conn.Do(radix.Cmd(nil, args[0], args[1:]...))
+	return
+}
+
+// GetTeardownCommands returns the FT.DROPINDEX of -index-name when -drop-index is set, so that dropping the index
+// ( with or without its documents ) is timed
+func (b *benchmark) GetTeardownCommands() [][]string {
+	if !dropIndex {
+		return nil
+	}
+	cmd := []string{"FT.DROPINDEX", indexName}
+	if dropIndexDD {
+		cmd = append(cmd, "DD")
+	}
+	return [][]string{cmd}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	radix "github.com/mediocregopher/radix/v3"
+	"github.com/mediocregopher/radix/v3/resp/resp2"
+	"os"
+	"strings"
+	"time"
+)
+
+// explainQueries issues FT.EXPLAIN for each unique FT.SEARCH and FT.AGGREGATE query of the input, writing the
+// query plans to fileName. Queries are deduplicated by index, query string and dialect. The queries are not
+// benchmarked, so the index is expected to exist already
+func explainQueries(br *bufio.Reader, fileName string) (explained int, err error) {
+	conn, err := radix.Dial("tcp", host, getDialOpts(time.Second*600)...)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	file, err := os.Create(fileName)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	w := bufio.NewWriter(file)
+	defer w.Flush()
+
+	seen := map[string]bool{}
+	decoder := (&benchmark{}).GetCmdDecoder(br)
+	for doc := decoder.Decode(br); doc != nil; doc = decoder.Decode(br) {
+		_, cmdQueryId, _, cmd, _, _, args, _, preErr := preProcessCmd(doc.Data.(string))
+		if preErr != nil {
+			return explained, preErr
+		}
+		if !isQueryCmd(cmd) || len(args) < 2 {
+			continue
+		}
+		explainArgs := []string{args[0], args[1]}
+		paramsPos, paramsNargs, paramsErr := queryParams(args)
+		if paramsErr != nil {
+			return explained, paramsErr
+		}
+		for pos := 2; pos < len(args)-1; pos++ {
+			if pos == paramsPos {
+				pos += 1 + paramsNargs
+				continue
+			}
+			if strings.ToUpper(args[pos]) == "DIALECT" {
+				explainArgs = append(explainArgs, "DIALECT", args[pos+1])
+			}
+		}
+		// parameterized queries are deduplicated by their template, and explained with the first bound values
+		key := strings.Join(explainArgs, "\x00")
+		if paramsPos >= 0 {
+			explainArgs = append(explainArgs, args[paramsPos:paramsPos+2+paramsNargs]...)
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		cmdQueryId, _, _, _ = parseQueryId(cmdQueryId)
+
+		var plan string
+		err = conn.Do(radix.Cmd(&plan, "FT.EXPLAIN", explainArgs...))
+		var respErr resp2.Error
+		if errors.As(err, &respErr) {
+			// invalid queries are reported on the file, without stopping
+			plan = fmt.Sprintf("ERROR: %v\n", respErr.E)
+		} else if err != nil {
+			return
+		}
+		if _, err = fmt.Fprintf(w, "# query id: %s\n# FT.EXPLAIN %s\n%s\n", cmdQueryId, strings.Join(explainArgs, " "), plan); err != nil {
+			return
+		}
+		explained++
+	}
+	err = nil
+	return
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	radix "github.com/mediocregopher/radix/v3"
+	"log"
+	"strings"
+	"time"
+)
+
+// ftConfigParam is a RediSearch configuration parameter set via -ft-config
+type ftConfigParam struct {
+	name  string
+	value string
+}
+
+// parseFTConfig parses the comma separated list of NAME=VALUE parameters of -ft-config
+func parseFTConfig(s string) (params []ftConfigParam, err error) {
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			err = fmt.Errorf("-ft-config expects a comma separated list of NAME=VALUE parameters. Got %s", s)
+			return
+		}
+		params = append(params, ftConfigParam{strings.ToUpper(strings.TrimSpace(kv[0])), strings.TrimSpace(kv[1])})
+	}
+	return
+}
+
+// applyFTConfig issues FT.CONFIG SET for each of the -ft-config parameters, before the benchmark, so that the
+// results are tied to a specific server tuning
+func applyFTConfig(params []ftConfigParam) (err error) {
+	conn, err := radix.Dial("tcp", host, getDialOpts(time.Second*10)...)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	for _, param := range params {
+		if err = conn.Do(radix.Cmd(nil, "FT.CONFIG", "SET", param.name, param.value)); err != nil {
+			return fmt.Errorf("FT.CONFIG SET %s %s failed: %v", param.name, param.value, err)
+		}
+		log.Printf("Applied FT.CONFIG SET %s %s\n", param.name, param.value)
+	}
+	return
+}
+
+// ftConfigMap returns the applied -ft-config parameters, to be recorded on the results
+func ftConfigMap(params []ftConfigParam) map[string]interface{} {
+	configs := map[string]interface{}{}
+	for _, param := range params {
+		configs[param.name] = param.value
+	}
+	return configs
+}
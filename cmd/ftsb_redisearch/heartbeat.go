@@ -0,0 +1,23 @@
+package main
+
+import (
+	radix "github.com/mediocregopher/radix/v3"
+	"time"
+)
+
+// Ping checks that the Redis server is still reachable, by issuing a PING on a dedicated connection.
+// The connection is re-established on the next call whenever an error occurs.
+func (b *benchmark) Ping(timeout time.Duration) (err error) {
+	if b.heartbeatConn == nil {
+		b.heartbeatConn, err = radix.Dial("tcp", host, getDialOpts(timeout)...)
+		if err != nil {
+			return
+		}
+	}
+	err = b.heartbeatConn.Do(radix.Cmd(nil, "PING"))
+	if err != nil {
+		b.heartbeatConn.Close()
+		b.heartbeatConn = nil
+	}
+	return
+}
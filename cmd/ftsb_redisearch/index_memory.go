@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	radix "github.com/mediocregopher/radix/v3"
+	"strconv"
+	"time"
+)
+
+// indexMemoryComponents are the FT.INFO memory fields ( in MB ) summed up as the index memory, on the versions
+// whose FT.INFO does not report the total_index_memory_sz_mb
+var indexMemoryComponents = []string{
+	"inverted_sz_mb",
+	"vector_index_sz_mb",
+	"offset_vectors_sz_mb",
+	"doc_table_size_mb",
+	"sortable_values_size_mb",
+	"key_table_size_mb",
+	"tag_overhead_sz_mb",
+	"text_overhead_sz_mb",
+}
+
+// GetIndexMemoryMap derives the -index-name memory per indexed document from its FT.INFO, once the benchmark is
+// done. It returns an empty map unless -capture-server-info and -index-name are set
+func (b *benchmark) GetIndexMemoryMap() (map[string]interface{}, error) {
+	configs := map[string]interface{}{}
+	if !captureServerInfo || indexName == "" {
+		return configs, nil
+	}
+	conn, err := radix.Dial("tcp", host, getDialOpts(time.Second*600)...)
+	if err != nil {
+		return configs, err
+	}
+	defer conn.Close()
+	ftInfoFields, err := ftInfoScalarFields(conn)
+	if err != nil {
+		return configs, err
+	}
+	numDocs, err := strconv.ParseInt(ftInfoFields["num_docs"], 10, 64)
+	if err != nil {
+		return configs, fmt.Errorf("FT.INFO %s reply has no num_docs", indexName)
+	}
+	source := "total_index_memory_sz_mb"
+	indexMB, err := strconv.ParseFloat(ftInfoFields[source], 64)
+	if err != nil {
+		source = "sum of the index structures sizes"
+		indexMB = 0.0
+		for _, field := range indexMemoryComponents {
+			if value, err := strconv.ParseFloat(ftInfoFields[field], 64); err == nil {
+				indexMB += value
+			}
+		}
+	}
+	configs["IndexName"] = indexName
+	configs["NumDocs"] = numDocs
+	configs["IndexMemoryBytes"] = indexMB * 1024 * 1024
+	configs["Source"] = source
+	// the ratio is undefined over an empty index
+	if numDocs > 0 {
+		configs["BytesPerDoc"] = indexMB * 1024 * 1024 / float64(numDocs)
+	}
+	return configs, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/RediSearch/ftsb/benchmark_runner"
+)
+
+const (
+	indexerRoundRobin = "round-robin"
+	indexerKeyHash    = "key-hash"
+	indexerSequential = "sequential"
+)
+
+// RedisIndexer distributes the commands across the work queues in a round-robin fashion
+type RedisIndexer struct {
+	partitions uint
+}
+
+func (i *RedisIndexer) GetIndex(itemsRead uint64, p *benchmark_runner.DocHolder) int {
+	return int(uint(itemsRead) % i.partitions)
+}
+
+// keyHashIndexer sends the commands of the same cluster slot to the same work queue, keeping the cluster
+// locality of each worker and the relative order of the commands over the same key.
+// Commands without a key fall back to round-robin
+type keyHashIndexer struct {
+	partitions uint
+}
+
+func (i *keyHashIndexer) GetIndex(itemsRead uint64, p *benchmark_runner.DocHolder) int {
+	_, _, _, _, _, clusterSlot, _, _, err := preProcessCmd(p.Data.(string))
+	if err != nil || clusterSlot < 0 {
+		return int(uint(itemsRead) % i.partitions)
+	}
+	return int(uint(clusterSlot) % i.partitions)
+}
+
+// sequentialIndexer sends all commands to the first work queue, so that they are issued by a single worker
+// in the input order
+type sequentialIndexer struct{}
+
+func (i *sequentialIndexer) GetIndex(_ uint64, _ *benchmark_runner.DocHolder) int {
+	return 0
+}
+
+// workQueues returns the number of work queues required by the chosen indexer: the round-robin one keeps
+// the single queue shared by all workers, while the remaining ones require each worker to have its own queue
+func workQueues() uint {
+	if indexer == indexerRoundRobin {
+		return benchmark_runner.SingleQueue
+	}
+	return benchmark_runner.WorkerPerQueue
+}
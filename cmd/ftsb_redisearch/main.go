@@ -4,18 +4,47 @@ import (
 	"bufio"
 	"flag"
 	"github.com/RediSearch/ftsb/benchmark_runner"
+	radix "github.com/mediocregopher/radix/v3"
 	"log"
+	"os"
+	"sync/atomic"
+	"time"
 )
 
 // Program option vars:
 var (
-	host          string
-	password      string
-	debug         int
-	loader        *benchmark_runner.BenchmarkRunner
-	pipeline      int
-	clusterMode   bool
-	continueOnErr bool
+	host                  string
+	password              string
+	debug                 int
+	loader                *benchmark_runner.BenchmarkRunner
+	pipeline              int
+	setupPipeline         int
+	pipelineAcrossBatches bool
+	pipelineFlushInterval time.Duration
+	pipelineJitter        int
+	autotunePipe          bool
+	autotuneStep          time.Duration
+	clusterMode           bool
+	continueOnErr         bool
+	verify                bool
+	cmdTimeout            time.Duration
+	inputFormat           string
+	explainOutFile        string
+	captureServerInfo     bool
+	indexName             string
+	prewarmConns          bool
+	indexer               string
+	queryTimeoutMs        int
+	noIndex               bool
+	dropIndex             bool
+	dropIndexDD           bool
+	ftConfig              string
+	thinkTime             time.Duration
+	noPool                bool
+	compressionSampleRate float64
+	verifyCount           bool
+	verifyCountTolerance  float64
+	ftConfigParams        []ftConfigParam
 )
 
 // Parse args:
@@ -23,14 +52,105 @@ func init() {
 	loader = benchmark_runner.GetBenchmarkRunnerWithBatchSize(10)
 	flag.StringVar(&host, "host", "localhost:6379", "The host:port for Redis connection")
 	flag.StringVar(&password, "a", "", "Password for Redis Auth.")
+	loader.RedactFlag("a")
 	flag.IntVar(&debug, "debug", 0, "Debug printing (choices: 0, 1, 2). (default 0)")
 	flag.BoolVar(&continueOnErr, "continue-on-error", false, "If set to true, it will continue the benchmark and print the error message to stderr.")
-	flag.BoolVar(&clusterMode, "cluster-mode", false, "If set to true, it will run the client in cluster mode.")
+	flag.BoolVar(&clusterMode, "cluster-mode", false, "If set to true, it will run the client in cluster mode. Automatically enabled when -host is a cluster node (probed via CLUSTER INFO).")
 	flag.IntVar(&pipeline, "pipeline", 1, "Pipeline <numreq> requests. Default 1 (no pipeline).")
+	flag.BoolVar(&pipelineAcrossBatches, "pipeline-across-batches", false, "If set to true, each worker keeps its pipelines across the input batches, flushing them once they reach the -pipeline depth or every -pipeline-flush-interval, instead of flushing them at the end of every batch. Decouples the pipeline depth from the batch size (100 rows).")
+	flag.DurationVar(&pipelineFlushInterval, "pipeline-flush-interval", time.Millisecond, "With -pipeline-across-batches, the interval at which the pipelines are flushed regardless of their depth, bounding the time a command waits on a pipeline for the following ones.")
+	flag.IntVar(&setupPipeline, "setup-pipeline", 0, "Pipeline <numreq> requests of the SETUP_WRITE commands (the index population), overriding -pipeline for them only, so that the setup is sped up without changing the pipeline of the measured commands. The SETUP_WRITE commands are never pipelined alongside other commands (0 = use -pipeline).")
+	flag.IntVar(&pipelineJitter, "pipeline-jitter", 0, "Randomly vary the number of requests pipelined by each worker by up to <numreq> requests (0 = disabled), smoothing the arrival of requests on the server instead of synchronized bursts.")
+	flag.BoolVar(&autotunePipe, "autotune-pipeline", false, "If set to true, -pipeline is ignored and the pipeline depths 1, 4, 16, 64 and 256 are swept at the start of the benchmark (each during -autotune-step), using the depth with the highest throughput for the remainder of the benchmark. The sweep commands are included on the results.")
+	flag.DurationVar(&autotuneStep, "autotune-step", 2*time.Second, "Duration of each pipeline depth sweep step. Requires -autotune-pipeline.")
+	flag.BoolVar(&noPool, "no-pool", false, "If set to true, each command dials a fresh connection, issues the command and closes the connection, instead of reusing the worker connection. Quantifies the cost of not pooling connections, given the connection setup is part of each command latency. Requires -pipeline 1.")
+	flag.BoolVar(&prewarmConns, "prewarm-conns", false, "If set to true, each worker connection is established and checked with a PING before the benchmark starts, so that the first commands latency does not include the connection setup.")
+	flag.StringVar(&indexer, "indexer", indexerRoundRobin, "Strategy used to distribute the input commands across the workers (choices: round-robin, key-hash, sequential). round-robin shares a single queue across all workers, key-hash sends the commands of the same cluster slot to the same worker (keeping the cluster locality and the order of the commands over the same key), and sequential issues all commands from a single worker in the input order.")
+	flag.IntVar(&queryTimeoutMs, "query-timeout-ms", 0, "If set, appends TIMEOUT <ms> to the FT.SEARCH and FT.AGGREGATE commands (that do not already set it), so that slow queries are cut off on the server side. The commands cut off by a timeout (partial results or timeout errors) are reported per query id (0 = disabled).")
+	flag.BoolVar(&noIndex, "no-index", false, "If set to true, the FT.* commands (besides the FT.SUG* suggestion ones) of the input and of the -json-config-file setup and teardown are not issued, loading the same data as plain hashes without any index. Comparing this baseline with an indexed load quantifies the indexing cost. Requires the documents to be written via HSET (FT.ADD and FT.DEL inputs are rejected).")
+	flag.BoolVar(&verify, "verify", false, "If set to true, the replies of the rows carrying expected results on the query id column (<queryId>|count=<n>|top=<docId>) are verified, and the mismatches reported.")
+	flag.BoolVar(&verifyCount, "verify-count", false, "If set to true, once the benchmark is done the distinct documents successfully written (and not deleted afterwards) are compared with the increase of the -index-name num_docs reported by FT.INFO, failing the run if they differ by more than -verify-count-tolerance. Assumes the benchmark writes new documents. Requires -index-name.")
+	flag.Float64Var(&verifyCountTolerance, "verify-count-tolerance", 0, "Percentage of the expected documents by which the index num_docs may differ before -verify-count fails the run.")
+	flag.DurationVar(&cmdTimeout, "cmd-timeout", 0, "Read and write timeout of each command (or pipeline). Timed out commands are accounted as errors (0 = no timeout besides the default 10 minutes connection timeout).")
+	flag.DurationVar(&thinkTime, "think-time", 0, "Delay inserted before each input row marked as dependent on the previous operation over the same key (<queryId>|dependent, like a read of a just written document), once that operation completed, modeling the think time of user sessions. The delay is not accounted on the latency. Dependent rows must be issued by the same worker as the operation they depend on, so use it alongside -indexer key-hash (0 = disabled).")
+	flag.StringVar(&inputFormat, "input-format", inputFormatCSV, "Format of the input rows (choices: csv, monitor). The monitor format replays a captured redis MONITOR output (or redis-cli command log), inferring the command type from each command name.")
+	flag.BoolVar(&captureServerInfo, "capture-server-info", false, "If set to true, INFO ( and FT.INFO of -index-name ) metrics like memory, indexed documents and indexing time are captured before and after the benchmark, and their deltas added to the results. With -index-name, the index memory per indexed document is also derived from the final FT.INFO and reported on the summary and results.")
+	flag.StringVar(&indexName, "index-name", "", "Index name whose FT.INFO is captured with -capture-server-info. If not set, only INFO is captured.")
+	flag.BoolVar(&dropIndex, "drop-index", false, "If set to true, FT.DROPINDEX of -index-name is issued after the benchmark (after the -json-config-file teardown), and its duration added to the results.")
+	flag.BoolVar(&dropIndexDD, "drop-index-dd", false, "If set to true, -drop-index also deletes the indexed documents (FT.DROPINDEX ... DD). Otherwise the documents are kept.")
+	flag.StringVar(&ftConfig, "ft-config", "", "Comma separated list of RediSearch configuration NAME=VALUE parameters (e.g. MINPREFIX=1,MAXEXPANSIONS=500,TIMEOUT=0) applied via FT.CONFIG SET on -host before the benchmark, and recorded on the results. If not set, the server configuration is left untouched.")
+	flag.Float64Var(&compressionSampleRate, "compression-sample-rate", 0, "Fraction of the commands (within [0,1]) whose RESP encoding is gzip compressed as a single stream, estimating the compression ratio of the transmitted commands, reported on the results. Informs whether a compressed connection would save bandwidth (0 = disabled).")
+	flag.StringVar(&explainOutFile, "explain-out-file", "", "If set, instead of benchmarking, issues FT.EXPLAIN for each unique FT.SEARCH and FT.AGGREGATE query of the input (against an already existing index) and writes the query plans to this file. Parameterized queries are explained once per query template.")
+}
+
+// parseFlags parses and validates the command line flags. It is not part of init, so that the test binary can
+// parse its own flags
+func parseFlags() {
 	flag.Parse()
+	envFlags, err := benchmark_runner.ApplyEnvOverrides()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, name := range envFlags {
+		log.Printf("Using -%s from the %s environment variable\n", name, benchmark_runner.EnvVarName(name))
+	}
+	if autotunePipe && autotuneStep <= 0 {
+		log.Fatalf("-autotune-step must be positive")
+	}
+	if inputFormat != inputFormatCSV && inputFormat != inputFormatMonitor {
+		log.Fatalf("invalid -input-format %s. Valid options are: %s, %s", inputFormat, inputFormatCSV, inputFormatMonitor)
+	}
+	if noPool && (pipeline != 1 || setupPipeline > 1 || pipelineJitter > 0 || autotunePipe) {
+		log.Fatalf("-no-pool issues each command on its own connection, so it can't be used with -pipeline, -setup-pipeline, -pipeline-jitter or -autotune-pipeline")
+	}
+	if pipelineAcrossBatches && pipelineFlushInterval <= 0 {
+		log.Fatalf("-pipeline-flush-interval must be positive")
+	}
+	if setupPipeline < 0 {
+		log.Fatalf("-setup-pipeline can't be negative")
+	}
+	if setupPipeline > 0 && autotunePipe {
+		log.Fatalf("-setup-pipeline can't be used with -autotune-pipeline, given the setup writes would skew the pipeline depths sweep")
+	}
+	if noPool && prewarmConns {
+		log.Fatalf("-no-pool has no connections to prewarm, so it can't be used with -prewarm-conns")
+	}
+	if compressionSampleRate < 0 || compressionSampleRate > 1 {
+		log.Fatalf("-compression-sample-rate must be within [0,1]")
+	}
+	if thinkTime < 0 {
+		log.Fatalf("-think-time can't be negative")
+	}
+	if queryTimeoutMs < 0 {
+		log.Fatalf("-query-timeout-ms can't be negative")
+	}
+	if (dropIndex || dropIndexDD) && indexName == "" {
+		log.Fatalf("-drop-index and -drop-index-dd require -index-name")
+	}
+	if dropIndexDD {
+		dropIndex = true
+	}
+	if verifyCount && indexName == "" {
+		log.Fatalf("-verify-count requires -index-name")
+	}
+	if verifyCount && flag.Lookup("concurrency-sweep").Value.String() != "" {
+		log.Fatalf("-verify-count can't be used with -concurrency-sweep, given each level replays the same documents")
+	}
+	if verifyCountTolerance < 0 || verifyCountTolerance > 100 {
+		log.Fatalf("-verify-count-tolerance must be within [0,100]")
+	}
+	if ftConfig != "" {
+		if ftConfigParams, err = parseFTConfig(ftConfig); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if indexer != indexerRoundRobin && indexer != indexerKeyHash && indexer != indexerSequential {
+		log.Fatalf("invalid -indexer %s. Valid options are: %s, %s, %s", indexer, indexerRoundRobin, indexerKeyHash, indexerSequential)
+	}
 }
 
 type benchmark struct {
+	heartbeatConn radix.Conn
 }
 
 func (b *benchmark) GetConfigurationParametersMap() map[string]interface{} {
@@ -40,17 +160,34 @@ func (b *benchmark) GetConfigurationParametersMap() map[string]interface{} {
 	configs["continueOnError"] = continueOnErr
 	configs["debug"] = debug
 	configs["pipeline"] = pipeline
+	configs["pipelineAcrossBatches"] = pipelineAcrossBatches
+	configs["pipelineFlushInterval"] = pipelineFlushInterval.String()
+	configs["setupPipeline"] = setupPipeline
+	configs["pipelineJitter"] = pipelineJitter
+	configs["autotunePipeline"] = autotunePipe
+	if autotunePipe {
+		configs["autotunedPipeline"] = tuner.chosenDepth()
+	}
+	configs["verify"] = verify
+	configs["cmdTimeout"] = cmdTimeout.String()
+	configs["inputFormat"] = inputFormat
+	configs["prewarmConns"] = prewarmConns
+	configs["captureServerInfo"] = captureServerInfo
+	configs["indexName"] = indexName
+	configs["indexer"] = indexer
+	configs["queryTimeoutMs"] = queryTimeoutMs
+	configs["noIndex"] = noIndex
+	configs["dropIndex"] = dropIndex
+	configs["dropIndexDD"] = dropIndexDD
+	configs["ftConfig"] = ftConfigMap(ftConfigParams)
+	configs["thinkTime"] = thinkTime.String()
+	configs["noPool"] = noPool
+	configs["compressionSampleRate"] = compressionSampleRate
+	configs["verifyCount"] = verifyCount
+	configs["verifyCountTolerance"] = verifyCountTolerance
 	return configs
 }
 
-type RedisIndexer struct {
-	partitions uint
-}
-
-func (i *RedisIndexer) GetIndex(itemsRead uint64, p *benchmark_runner.DocHolder) int {
-	return int(uint(itemsRead) % i.partitions)
-}
-
 func (b *benchmark) GetCmdDecoder(br *bufio.Reader) benchmark_runner.DocDecoder {
 	scanner := bufio.NewScanner(br)
 	buf := make([]byte, 0, 64*1024)
@@ -63,14 +200,39 @@ func (b *benchmark) GetBatchFactory() benchmark_runner.BatchFactory {
 }
 
 func (b *benchmark) GetCommandIndexer(maxPartitions uint) benchmark_runner.DocIndexer {
-	return &RedisIndexer{partitions: maxPartitions}
+	switch indexer {
+	case indexerKeyHash:
+		return &keyHashIndexer{partitions: maxPartitions}
+	case indexerSequential:
+		return &sequentialIndexer{}
+	default:
+		return &RedisIndexer{partitions: maxPartitions}
+	}
 }
 
 func (b *benchmark) GetProcessor() benchmark_runner.Processor {
 	return &processor{}
 }
 
+// ResetRunState clears the tallies accumulated while issuing the workload, so that each level of a
+// -concurrency-sweep reports its own
+func (b *benchmark) ResetRunState() {
+	verification.reset()
+	nodesDistribution.reset()
+	reconnects.reset()
+	txCompression.reset()
+	tuner.reset()
+	loadedDocs.reset()
+}
+
 func main() {
+	parseFlags()
+	if flag.NArg() > 0 && flag.Arg(0) == "compare" {
+		os.Exit(compareCmd(flag.Args()[1:]))
+	}
+	if flag.NArg() > 0 && flag.Arg(0) == "smoke" {
+		os.Exit(smokeCmd(flag.Args()[1:]))
+	}
 	b := benchmark{}
 	git_sha := toolGitSHA1()
 	git_dirty_str := ""
@@ -78,5 +240,23 @@ func main() {
 		git_dirty_str = "-dirty"
 	}
 	log.Printf("ftsb (git_sha1:%s%s)\n", git_sha, git_dirty_str)
-	loader.RunBenchmark(&b, benchmark_runner.SingleQueue)
+	loader.SetVersion(git_sha + git_dirty_str)
+	if explainOutFile != "" {
+		explained, err := explainQueries(loader.GetBufferedReader(), explainOutFile)
+		if err != nil {
+			log.Fatalf("error while explaining the input queries: %v", err)
+		}
+		log.Printf("wrote the query plans of %d unique queries to %s\n", explained, explainOutFile)
+		return
+	}
+	detectClusterMode()
+	if len(ftConfigParams) > 0 {
+		if err := applyFTConfig(ftConfigParams); err != nil {
+			log.Fatalf("error while applying -ft-config: %v", err)
+		}
+	}
+	loader.RunBenchmark(&b, workQueues())
+	if skipped := atomic.LoadUint64(&noIndexSkipped); skipped > 0 {
+		log.Printf("%d input commands requiring an index were not issued, given -no-index\n", skipped)
+	}
 }
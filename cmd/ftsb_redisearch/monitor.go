@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	inputFormatCSV     = "csv"
+	inputFormatMonitor = "monitor"
+)
+
+// monitorPrefix matches the timestamp and client details MONITOR adds before each command, e.g.
+// 1339518083.107412 [0 127.0.0.1:60866] "keys" "*"
+var monitorPrefix = regexp.MustCompile(`^\d+\.\d+ \[\d+ [^\]]*\] `)
+
+// monitorCmdTypes maps the replayed command names to the benchmark command types.
+// Commands not present here ( like administrative ones ) are skipped
+var monitorCmdTypes = map[string]string{
+	"FT.SEARCH":     "READ",
+	"FT.AGGREGATE":  "READ",
+	"FT.SPELLCHECK": "READ",
+	"FT.SUGGET":     "READ",
+	"FT.TAGVALS":    "READ",
+	"FT.GET":        "READ",
+	"FT.MGET":       "READ",
+	"GET":           "READ",
+	"HGET":          "READ",
+	"HMGET":         "READ",
+	"HGETALL":       "READ",
+	"JSON.GET":      "READ",
+	"FT.CURSOR":     "CURSOR_READ",
+	"FT.ADD":        "WRITE",
+	"FT.SUGADD":     "WRITE",
+	"SET":           "WRITE",
+	"HSET":          "WRITE",
+	"HMSET":         "WRITE",
+	"JSON.SET":      "WRITE",
+	"FT.DEL":        "DELETE",
+	"DEL":           "DELETE",
+	"UNLINK":        "DELETE",
+}
+
+// monitorKeyPositions holds the commands whose key ( used for cluster slot routing ) is not the first argument
+var monitorKeyPositions = map[string]int{
+	"FT.ADD": 2,
+	"FT.DEL": 2,
+	"FT.GET": 2,
+}
+
+// monitorLineToRow converts a MONITOR output line ( or a redis-cli like command line ) into an input row.
+// ok is false for the lines that are not commands or whose command is not replayed
+func monitorLineToRow(line string) (row string, ok bool, err error) {
+	line = monitorPrefix.ReplaceAllString(strings.TrimSpace(line), "")
+	args, err := splitCommandArgs(line)
+	if err != nil || len(args) == 0 {
+		return
+	}
+	cmd := strings.ToUpper(args[0])
+	cmdType, known := monitorCmdTypes[cmd]
+	if !known {
+		return
+	}
+	keyPos := 1
+	if pos, exists := monitorKeyPositions[cmd]; exists {
+		keyPos = pos
+	}
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	err = w.Write(append([]string{cmdType, cmd, strconv.Itoa(keyPos), cmd}, args[1:]...))
+	if err != nil {
+		return
+	}
+	w.Flush()
+	row = strings.TrimSuffix(buf.String(), "\n")
+	ok = true
+	return
+}
+
+// splitCommandArgs splits a command line into its arguments, following the same quoting rules as redis-cli and
+// MONITOR: arguments are separated by spaces, and can be double quoted ( with \" \\ \n \r \t \b \a and \xHH escapes )
+// or single quoted ( with \' escapes )
+func splitCommandArgs(line string) (args []string, err error) {
+	pos := 0
+	for {
+		for pos < len(line) && line[pos] == ' ' {
+			pos++
+		}
+		if pos >= len(line) {
+			return
+		}
+		var arg strings.Builder
+		switch line[pos] {
+		case '"':
+			pos++
+			for {
+				if pos >= len(line) {
+					err = fmt.Errorf("unbalanced double quotes on line: %s", line)
+					return
+				}
+				c := line[pos]
+				if c == '"' {
+					pos++
+					break
+				}
+				if c == '\\' && pos+1 < len(line) {
+					pos++
+					switch line[pos] {
+					case 'n':
+						arg.WriteByte('\n')
+					case 'r':
+						arg.WriteByte('\r')
+					case 't':
+						arg.WriteByte('\t')
+					case 'b':
+						arg.WriteByte('\b')
+					case 'a':
+						arg.WriteByte('\a')
+					case 'x':
+						if pos+2 < len(line) {
+							if v, convErr := strconv.ParseUint(line[pos+1:pos+3], 16, 8); convErr == nil {
+								arg.WriteByte(byte(v))
+								pos += 2
+								break
+							}
+						}
+						arg.WriteByte('x')
+					default:
+						arg.WriteByte(line[pos])
+					}
+				} else {
+					arg.WriteByte(c)
+				}
+				pos++
+			}
+		case '\'':
+			pos++
+			for {
+				if pos >= len(line) {
+					err = fmt.Errorf("unbalanced single quotes on line: %s", line)
+					return
+				}
+				c := line[pos]
+				if c == '\'' {
+					pos++
+					break
+				}
+				if c == '\\' && pos+1 < len(line) && line[pos+1] == '\'' {
+					pos++
+					c = '\''
+				}
+				arg.WriteByte(c)
+				pos++
+			}
+		default:
+			for pos < len(line) && line[pos] != ' ' {
+				arg.WriteByte(line[pos])
+				pos++
+			}
+		}
+		args = append(args, arg.String())
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+// noIndexSkipped counts the input commands that were not issued given -no-index
+var noIndexSkipped uint64
+
+// requiresIndex returns true for the RediSearch commands that operate on an index. The suggestion dictionaries
+// ( FT.SUG* ) are standalone keys, so they don't require one
+func requiresIndex(cmd string) bool {
+	cmd = strings.ToUpper(cmd)
+	return strings.HasPrefix(cmd, "FT.") && !strings.HasPrefix(cmd, "FT.SUG")
+}
+
+// storesOnIndex returns true for the commands that store the documents on the index itself, and so can't be
+// replaced by plain hash commands on a no index baseline
+func storesOnIndex(cmd string) bool {
+	return strings.EqualFold(cmd, "FT.ADD") || strings.EqualFold(cmd, "FT.DEL")
+}
+
+// skipNoIndexCmd returns true, accounting for it, if the command is not to be issued given -no-index
+func skipNoIndexCmd(cmd string) bool {
+	if !noIndex || !requiresIndex(cmd) {
+		return false
+	}
+	atomic.AddUint64(&noIndexSkipped, 1)
+	return true
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	radix "github.com/mediocregopher/radix/v3"
+)
+
+// dialPerCmdClient is a radix.Client that dials a fresh connection for each command, closing it right after,
+// modeling the clients that don't pool their connections. The dial and close are part of each command latency
+type dialPerCmdClient struct {
+	network string
+	addr    string
+	opts    []radix.DialOpt
+}
+
+func (c *dialPerCmdClient) Do(a radix.Action) error {
+	conn, err := radix.Dial(c.network, c.addr, c.opts...)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return a.Run(conn)
+}
+
+func (c *dialPerCmdClient) Close() error {
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// isQueryCmd returns true for the commands carrying an index, a query string and the query options
+func isQueryCmd(cmd string) bool {
+	return strings.EqualFold(cmd, "FT.SEARCH") || strings.EqualFold(cmd, "FT.AGGREGATE")
+}
+
+// queryParams locates the PARAMS clause of FT.SEARCH/FT.AGGREGATE args ( index, query and options ), returning the
+// position of the PARAMS keyword and the number of name value arguments following it. pos is -1 when the query is
+// not parameterized
+func queryParams(args []string) (pos int, nargs int, err error) {
+	pos = -1
+	for i := 2; i < len(args); i++ {
+		if !strings.EqualFold(args[i], "PARAMS") {
+			continue
+		}
+		if i+1 >= len(args) {
+			err = fmt.Errorf("missing PARAMS arguments count on query: %s", strings.Join(args, " "))
+			return
+		}
+		nargs, err = strconv.Atoi(args[i+1])
+		if err != nil || nargs <= 0 || nargs%2 != 0 || i+1+nargs >= len(args) {
+			err = fmt.Errorf("PARAMS expects an even number of name value arguments, got %s on query: %s", args[i+1], strings.Join(args, " "))
+			return
+		}
+		pos = i
+		return
+	}
+	return
+}
+
+// hasOption returns true if any of the FT.SEARCH/FT.AGGREGATE options matches the given one, case insensitively.
+// The index, the query string and the values bound via PARAMS are not options, so they are skipped
+func hasOption(args []string, opt string) bool {
+	pos, nargs, _ := queryParams(args)
+	for i := 2; i < len(args); i++ {
+		if i == pos {
+			i += 1 + nargs
+			continue
+		}
+		if strings.EqualFold(args[i], opt) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strconv"
+	"sync"
+)
+
+// reconnectsTally keeps track of the connections re-established by each worker after its initialization,
+// surfacing server instability or resource limits that would otherwise hide behind slightly elevated latencies
+type reconnectsTally struct {
+	mutex     sync.Mutex
+	perWorker map[int]uint64
+}
+
+var reconnects = &reconnectsTally{
+	perWorker: map[int]uint64{},
+}
+
+// reset clears the tally, between the levels of a -concurrency-sweep
+func (r *reconnectsTally) reset() {
+	r.mutex.Lock()
+	r.perWorker = map[int]uint64{}
+	r.mutex.Unlock()
+}
+
+func (r *reconnectsTally) record(workerNumber int) {
+	r.mutex.Lock()
+	r.perWorker[workerNumber]++
+	r.mutex.Unlock()
+}
+
+// GetReconnectsMap returns the number of re-established connections, per worker and overall
+func (b *benchmark) GetReconnectsMap() map[string]interface{} {
+	configs := map[string]interface{}{}
+	reconnects.mutex.Lock()
+	defer reconnects.mutex.Unlock()
+	total := uint64(0)
+	perWorker := map[string]interface{}{}
+	for workerNumber, count := range reconnects.perWorker {
+		perWorker[strconv.Itoa(workerNumber)] = count
+		total += count
+	}
+	configs["TotalReconnects"] = total
+	configs["PerWorker"] = perWorker
+	return configs
+}
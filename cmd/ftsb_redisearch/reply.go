@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/mediocregopher/radix/v3/resp/resp2"
+	"log"
+	"time"
+)
+
+// queryTimeoutMarker is how RediSearch signals a query cut off by its timeout: either as an error reply
+// ( ON_TIMEOUT FAIL ) or as an error element within the partial results ( ON_TIMEOUT RETURN )
+var queryTimeoutMarker = []byte("-Timeout limit was reached")
+var queryTimeoutElement = append([]byte("\r\n"), queryTimeoutMarker...)
+
+// cmdReply receives the reply of a command, accounting for its size in bytes and, on -verify mode,
+// checking it against the expected results of the input row
+type cmdReply struct {
+	queryId      string
+	exp          *expectation
+	rxBytesCount uint64
+	// when the first byte of the reply was available to be read
+	firstByteAt time.Time
+	// the query was cut off by a server side timeout
+	queryTimedOut bool
+	// error reply, if any
+	err error
+}
+
+func (r *cmdReply) UnmarshalRESP(br *bufio.Reader) error {
+	// on pipelines the later replies may have already been buffered while reading the previous ones,
+	// in which case this is when the reply started being decoded
+	if _, err := br.Peek(1); err != nil {
+		return err
+	}
+	r.firstByteAt = time.Now()
+	var raw resp2.RawMessage
+	if err := raw.UnmarshalRESP(br); err != nil {
+		return err
+	}
+	r.rxBytesCount = uint64(len(raw))
+	r.queryTimedOut = bytes.HasPrefix(raw, queryTimeoutMarker) || bytes.Contains(raw, queryTimeoutElement)
+	if r.queryTimedOut && !bytes.HasPrefix(raw, queryTimeoutMarker) {
+		// partial results are accounted as a query timeout but not as an error, and can't be verified
+		return nil
+	}
+	if !verify || r.exp == nil {
+		// discard the reply content, while still returning the error replies
+		r.err = raw.UnmarshalInto(resp2.Any{})
+		return r.err
+	}
+	var reply interface{}
+	if r.err = raw.UnmarshalInto(resp2.Any{I: &reply}); r.err != nil {
+		return r.err
+	}
+	matched, reason := r.exp.check(reply)
+	if !matched && debug > 0 {
+		log.Printf("Verification mismatch on query id %s: %s\n", r.queryId, reason)
+	}
+	verification.record(r.queryId, matched)
+	return nil
+}
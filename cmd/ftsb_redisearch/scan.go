@@ -15,13 +15,28 @@ type decoder struct {
 // Since scanning happens in a single thread, we hold off on transforming it
 // to an INSERT statement until it's being processed concurrently by a worker.
 func (d *decoder) Decode(_ *bufio.Reader) *benchmark_runner.DocHolder {
-	ok := d.scanner.Scan()
-	if !ok && d.scanner.Err() == nil { // nothing scanned & no error = EOF
-		return nil
-	} else if !ok {
-		log.Fatalf("scan error: %v", d.scanner.Err())
+	for {
+		ok := d.scanner.Scan()
+		if !ok && d.scanner.Err() == nil { // nothing scanned & no error = EOF
+			return nil
+		} else if !ok {
+			log.Fatalf("scan error: %v", d.scanner.Err())
+		}
+		if inputFormat != inputFormatMonitor {
+			return benchmark_runner.NewDocument(d.scanner.Text())
+		}
+		// convert the captured commands into input rows, skipping the ones that are not replayed
+		row, replayed, err := monitorLineToRow(d.scanner.Text())
+		if err != nil {
+			log.Fatalf("error while converting monitor line: %v", err)
+		}
+		if replayed {
+			return benchmark_runner.NewDocument(row)
+		}
+		if debug > 0 {
+			log.Printf("skipping monitor line: %s\n", d.scanner.Text())
+		}
 	}
-	return benchmark_runner.NewDocument(d.scanner.Text())
 }
 
 type eventsBatch struct {
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	radix "github.com/mediocregopher/radix/v3"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// serverInfoMetrics are the INFO fields captured by -capture-server-info
+var serverInfoMetrics = []string{
+	"used_memory",
+	"used_memory_rss",
+	"used_memory_dataset",
+	"total_commands_processed",
+	"total_net_input_bytes",
+	"total_net_output_bytes",
+}
+
+// ftInfoMetrics are the FT.INFO fields captured by -capture-server-info, when an -index-name is set
+var ftInfoMetrics = []string{
+	"num_docs",
+	"num_terms",
+	"num_records",
+	"inverted_sz_mb",
+	"offset_vectors_sz_mb",
+	"doc_table_size_mb",
+	"sortable_values_size_mb",
+	"key_table_size_mb",
+	"vector_index_sz_mb",
+	"tag_overhead_sz_mb",
+	"text_overhead_sz_mb",
+	"total_index_memory_sz_mb",
+	"total_indexing_time",
+}
+
+// CaptureServerInfo snapshots the INFO and FT.INFO metrics of the server. It returns an empty map if
+// -capture-server-info is not set
+func (b *benchmark) CaptureServerInfo() (snapshot map[string]float64, err error) {
+	snapshot = map[string]float64{}
+	if !captureServerInfo {
+		return
+	}
+	conn, err := radix.Dial("tcp", host, getDialOpts(time.Second*600)...)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var info string
+	if err = conn.Do(radix.Cmd(&info, "INFO")); err != nil {
+		return
+	}
+	infoFields := map[string]string{}
+	for _, line := range strings.Split(info, "\n") {
+		kv := strings.SplitN(strings.TrimSpace(line), ":", 2)
+		if len(kv) == 2 {
+			infoFields[kv[0]] = kv[1]
+		}
+	}
+	addNumericMetrics(snapshot, serverInfoMetrics, infoFields)
+
+	if indexName == "" {
+		return
+	}
+	ftInfoFields, err := ftInfoScalarFields(conn)
+	if err != nil {
+		return
+	}
+	addNumericMetrics(snapshot, ftInfoMetrics, ftInfoFields)
+	return
+}
+
+// ftInfoScalarFields returns the scalar fields of the -index-name FT.INFO reply
+func ftInfoScalarFields(conn radix.Conn) (map[string]string, error) {
+	// decode into an interface{}, given the reply mixes scalars and nested arrays
+	var reply interface{}
+	if err := conn.Do(radix.Cmd(&reply, "FT.INFO", indexName)); err != nil {
+		return nil, fmt.Errorf("FT.INFO %s failed: %v", indexName, err)
+	}
+	ftInfo, _ := reply.([]interface{})
+	ftInfoFields := map[string]string{}
+	// the reply alternates field names and values. Only the scalar values are kept
+	for pos := 0; pos+1 < len(ftInfo); pos += 2 {
+		key, isKey := replyString(ftInfo[pos])
+		value, isValue := replyString(ftInfo[pos+1])
+		if isKey && isValue {
+			ftInfoFields[key] = value
+		}
+	}
+	return ftInfoFields, nil
+}
+
+// addNumericMetrics adds the listed fields to the snapshot, skipping the missing and non numeric ones
+func addNumericMetrics(snapshot map[string]float64, metrics []string, fields map[string]string) {
+	for _, metric := range metrics {
+		if value, err := strconv.ParseFloat(fields[metric], 64); err == nil {
+			snapshot[metric] = value
+		}
+	}
+}
+
+// replyString converts a scalar reply element to its string form
+func replyString(v interface{}) (string, bool) {
+	switch x := v.(type) {
+	case []byte:
+		return string(x), true
+	case string:
+		return x, true
+	case int64:
+		return strconv.FormatInt(x, 10), true
+	}
+	return "", false
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	radix "github.com/mediocregopher/radix/v3"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+)
+
+// smokeMinDocs is the minimum number of documents of the smoke test, given the range query expects 10 results
+const smokeMinDocs = 10
+
+// smokeQuery is a query of the smoke test input, alongside its expected results count and top document
+type smokeQuery struct {
+	queryId  string
+	count    int
+	topDocId string
+	args     []string
+}
+
+// smokeQueries returns the queries of the smoke test over docs documents, whose expected results are known
+func smokeQueries(index, prefix string, docs int) []smokeQuery {
+	return []smokeQuery{
+		{"all-docs", docs, "", []string{index, "smoke", "NOCONTENT"}},
+		{"term", docs / 2, "", []string{index, "even", "NOCONTENT"}},
+		{"numeric-range", 10, prefix + "1", []string{index, "@price:[1 10]", "SORTBY", "price", "ASC", "NOCONTENT"}},
+	}
+}
+
+// writeSmokeInput writes the smoke test input: the index creation and the documents as SETUP_WRITE rows, followed by
+// the queries as READ rows carrying their expected results ( verified via -verify )
+func writeSmokeInput(fileName, index, prefix string, docs int, queries []smokeQuery) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(file)
+	w.Write([]string{"SETUP_WRITE", "create", "1", "FT.CREATE", index, "ON", "HASH", "PREFIX", "1", prefix,
+		"SCHEMA", "title", "TEXT", "price", "NUMERIC", "SORTABLE"})
+	for n := 1; n <= docs; n++ {
+		parity := "odd"
+		if n%2 == 0 {
+			parity = "even"
+		}
+		w.Write([]string{"SETUP_WRITE", "load", "1", "HSET", prefix + strconv.Itoa(n), "title", "smoke " + parity,
+			"price", strconv.Itoa(n)})
+	}
+	for _, q := range queries {
+		queryId := fmt.Sprintf("%s|count=%d", q.queryId, q.count)
+		if q.topDocId != "" {
+			queryId = fmt.Sprintf("%s|top=%s", queryId, q.topDocId)
+		}
+		w.Write(append([]string{"READ", queryId, "1", "FT.SEARCH"}, q.args...))
+	}
+	w.Flush()
+	if err = w.Error(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// smokeCmd implements the smoke subcommand, validating an installation end to end: it creates a small index, loads a
+// handful of documents and verifies the replies of a few queries through the regular benchmark pipeline, and drops
+// the index. It returns the process exit code: 1 when any step fails, 2 on usage errors
+func smokeCmd(args []string) int {
+	fs := flag.NewFlagSet("smoke", flag.ContinueOnError)
+	fs.StringVar(&host, "host", host, "The host:port for Redis connection")
+	fs.StringVar(&password, "a", password, "Password for Redis Auth.")
+	index := fs.String("index-name", "ftsb-smoke", "Name of the index created ( and dropped ) by the smoke test. Its documents are prefixed by <index-name>:. The smoke test fails if the index already exists.")
+	docs := fs.Int("docs", 100, fmt.Sprintf("Number of documents loaded by the smoke test. At least %d.", smokeMinDocs))
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s smoke [-host <host:port>] [-a <password>] [-index-name <name>] [-docs <n>]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 0 || *docs < smokeMinDocs {
+		fs.Usage()
+		return 2
+	}
+	failed := false
+	step := func(name string, err error) bool {
+		if err != nil {
+			fmt.Printf("FAIL %s: %v\n", name, err)
+			failed = true
+			return false
+		}
+		fmt.Printf("PASS %s\n", name)
+		return true
+	}
+
+	conn, err := radix.Dial("tcp", host, getDialOpts(time.Second*10)...)
+	if err == nil {
+		defer conn.Close()
+		err = conn.Do(radix.Cmd(nil, "PING"))
+	}
+	if !step(fmt.Sprintf("connect to %s", host), err) {
+		return 1
+	}
+	if conn.Do(radix.Cmd(nil, "FT.INFO", *index)) == nil {
+		step("check the index", fmt.Errorf("index %s already exists. Pick another one via -index-name", *index))
+		return 1
+	}
+	detectClusterMode()
+
+	prefix := *index + ":"
+	queries := smokeQueries(*index, prefix, *docs)
+	input, err := ioutil.TempFile("", "ftsb-smoke-*.csv")
+	if err == nil {
+		input.Close()
+		defer os.Remove(input.Name())
+		err = writeSmokeInput(input.Name(), *index, prefix, *docs, queries)
+	}
+	if !step("write the smoke input", err) {
+		return 1
+	}
+
+	// the input is issued in order by a single worker, so that the queries are issued once the documents are loaded,
+	// carrying on after errors so that the index is dropped anyway
+	flag.Set("input", input.Name())
+	flag.Set("workers", "1")
+	verify = true
+	continueOnErr = true
+	b := benchmark{}
+	loader.RunBenchmark(&b, workQueues())
+
+	errorCount := loader.GetTotalsMap()["Errors"].(uint64)
+	if errorCount > 0 {
+		err = fmt.Errorf("%d commands failed ( rerun with -debug 1 for the errors )", errorCount)
+	}
+	step(fmt.Sprintf("create the index and load %d documents", *docs), err)
+	verification := b.GetVerificationMap()
+	err = nil
+	if checked := verification["TotalChecked"].(uint64); checked != uint64(len(queries)) {
+		err = fmt.Errorf("%d of the %d queries were verified", checked, len(queries))
+	} else if mismatches := verification["TotalMismatches"].(uint64); mismatches > 0 {
+		err = fmt.Errorf("%d queries replied unexpected results ( rerun with -debug 1 for the mismatches )", mismatches)
+	}
+	step(fmt.Sprintf("verify %d queries", len(queries)), err)
+	step("drop the index", conn.Do(radix.Cmd(nil, "FT.DROPINDEX", *index, "DD")))
+
+	if failed {
+		fmt.Println("smoke test FAILED")
+		return 1
+	}
+	fmt.Println("smoke test PASSED")
+	return 0
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// expectation holds the expected reply of an input row, carried on the query id column as
+// <queryId>|count=<expected result count>|top=<expected top document id>
+type expectation struct {
+	count    int64 // -1 when not specified
+	topDocId string
+}
+
+// parseQueryId splits the query id column into the query id itself and the suffixes of the enabled features: the
+// expected results ( |count=<n>|top=<docId>, with -verify ) and whether the row depends on the previous operation
+// over the same key ( |dependent, with -think-time ). Any other suffix is kept as part of the query id
+func parseQueryId(field string) (queryId string, exp *expectation, dependent bool, err error) {
+	parts := strings.Split(field, "|")
+	queryId = parts[0]
+	for _, part := range parts[1:] {
+		switch {
+		case thinkTime > 0 && part == "dependent":
+			dependent = true
+		case verify && strings.HasPrefix(part, "count="):
+			if exp == nil {
+				exp = &expectation{count: -1}
+			}
+			exp.count, err = strconv.ParseInt(strings.TrimPrefix(part, "count="), 10, 64)
+			if err != nil {
+				err = fmt.Errorf("malformed expected count %s on query id %s", part, field)
+				return
+			}
+		case verify && strings.HasPrefix(part, "top="):
+			if exp == nil {
+				exp = &expectation{count: -1}
+			}
+			exp.topDocId = strings.TrimPrefix(part, "top=")
+		default:
+			queryId += "|" + part
+		}
+	}
+	return
+}
+
+// verificationTally keeps track of the checked replies and mismatches, per query id
+type verificationTally struct {
+	mutex      sync.Mutex
+	checked    map[string]uint64
+	mismatches map[string]uint64
+}
+
+var verification = &verificationTally{
+	checked:    map[string]uint64{},
+	mismatches: map[string]uint64{},
+}
+
+// reset clears the tally, between the levels of a -concurrency-sweep
+func (v *verificationTally) reset() {
+	v.mutex.Lock()
+	v.checked = map[string]uint64{}
+	v.mismatches = map[string]uint64{}
+	v.mutex.Unlock()
+}
+
+func (v *verificationTally) record(queryId string, matched bool) {
+	v.mutex.Lock()
+	v.checked[queryId]++
+	if !matched {
+		v.mismatches[queryId]++
+	}
+	v.mutex.Unlock()
+}
+
+// check compares a FT.SEARCH/FT.AGGREGATE reply ( the results count followed by the results ) against the expectation
+func (e *expectation) check(reply interface{}) (matched bool, reason string) {
+	arr, ok := reply.([]interface{})
+	if !ok || len(arr) == 0 {
+		return false, fmt.Sprintf("unexpected reply %v", reply)
+	}
+	count, ok := arr[0].(int64)
+	if !ok {
+		return false, fmt.Sprintf("unexpected results count %v", arr[0])
+	}
+	if e.count >= 0 && count != e.count {
+		return false, fmt.Sprintf("expected %d results, got %d", e.count, count)
+	}
+	if e.topDocId != "" {
+		top := ""
+		if len(arr) > 1 {
+			switch x := arr[1].(type) {
+			case []byte:
+				top = string(x)
+			case string:
+				top = x
+			}
+		}
+		if top != e.topDocId {
+			return false, fmt.Sprintf("expected top document %s, got %s", e.topDocId, top)
+		}
+	}
+	return true, ""
+}
+
+// GetVerificationMap returns the verification tally, per query id and overall
+func (b *benchmark) GetVerificationMap() map[string]interface{} {
+	configs := map[string]interface{}{}
+	if !verify {
+		return configs
+	}
+	verification.mutex.Lock()
+	defer verification.mutex.Unlock()
+	totalChecked := uint64(0)
+	totalMismatches := uint64(0)
+	perQueryId := map[string]interface{}{}
+	for queryId, checked := range verification.checked {
+		mismatches := verification.mismatches[queryId]
+		perQueryId[queryId] = map[string]interface{}{"Checked": checked, "Mismatches": mismatches}
+		totalChecked += checked
+		totalMismatches += mismatches
+	}
+	configs["TotalChecked"] = totalChecked
+	configs["TotalMismatches"] = totalMismatches
+	configs["PerQueryId"] = perQueryId
+	return configs
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	radix "github.com/mediocregopher/radix/v3"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// loadedDocsTally keeps track of the distinct documents successfully written ( and not deleted afterwards ) during
+// the benchmark, alongside the number of documents of the index before it, enabling -verify-count
+type loadedDocsTally struct {
+	mutex  sync.Mutex
+	live   map[string]struct{}
+	before int64
+}
+
+var loadedDocs = &loadedDocsTally{
+	live: map[string]struct{}{},
+}
+
+// writesDoc returns true for the commands that create ( or overwrite ) a document
+func writesDoc(cmd string) bool {
+	switch strings.ToUpper(cmd) {
+	case "HSET", "HMSET", "FT.ADD", "JSON.SET":
+		return true
+	}
+	return false
+}
+
+// deletesDoc returns true for the commands that delete a document
+func deletesDoc(cmd string) bool {
+	switch strings.ToUpper(cmd) {
+	case "DEL", "UNLINK", "FT.DEL":
+		return true
+	}
+	return false
+}
+
+// reset clears the tally, between the levels of a -concurrency-sweep
+func (t *loadedDocsTally) reset() {
+	t.mutex.Lock()
+	t.live = map[string]struct{}{}
+	t.before = 0
+	t.mutex.Unlock()
+}
+
+// record accounts for a successfully replied command over the document key
+func (t *loadedDocsTally) record(cmd string, key string) {
+	if !verifyCount || key == "" {
+		return
+	}
+	if writesDoc(cmd) {
+		t.mutex.Lock()
+		t.live[key] = struct{}{}
+		t.mutex.Unlock()
+	} else if deletesDoc(cmd) {
+		t.mutex.Lock()
+		delete(t.live, key)
+		t.mutex.Unlock()
+	}
+}
+
+// indexNumDocs returns the num_docs of -index-name, as reported by FT.INFO
+func indexNumDocs() (int64, error) {
+	conn, err := radix.Dial("tcp", host, getDialOpts(time.Second*600)...)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	var reply interface{}
+	if err = conn.Do(radix.Cmd(&reply, "FT.INFO", indexName)); err != nil {
+		return 0, fmt.Errorf("FT.INFO %s failed: %v", indexName, err)
+	}
+	ftInfo, _ := reply.([]interface{})
+	for pos := 0; pos+1 < len(ftInfo); pos += 2 {
+		if key, _ := replyString(ftInfo[pos]); key == "num_docs" {
+			value, _ := replyString(ftInfo[pos+1])
+			return strconv.ParseInt(value, 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("FT.INFO %s reply has no num_docs", indexName)
+}
+
+// StartCountVerification captures the number of documents of the index before the benchmark, given -verify-count
+func (b *benchmark) StartCountVerification() (err error) {
+	if !verifyCount {
+		return
+	}
+	loadedDocs.mutex.Lock()
+	defer loadedDocs.mutex.Unlock()
+	loadedDocs.live = map[string]struct{}{}
+	loadedDocs.before, err = indexNumDocs()
+	return
+}
+
+// GetCountVerificationMap compares the documents loaded during the benchmark with the increase of the index
+// num_docs. It returns an empty map if -verify-count is not set
+func (b *benchmark) GetCountVerificationMap() (map[string]interface{}, error) {
+	configs := map[string]interface{}{}
+	if !verifyCount {
+		return configs, nil
+	}
+	after, err := indexNumDocs()
+	if err != nil {
+		return configs, err
+	}
+	loadedDocs.mutex.Lock()
+	defer loadedDocs.mutex.Unlock()
+	expected := int64(len(loadedDocs.live))
+	actual := after - loadedDocs.before
+	difference := actual - expected
+	allowed := math.Floor(float64(expected) * verifyCountTolerance / 100.0)
+	configs["Expected"] = expected
+	configs["Actual"] = actual
+	configs["NumDocsBefore"] = loadedDocs.before
+	configs["NumDocsAfter"] = after
+	configs["Difference"] = difference
+	configs["TolerancePct"] = verifyCountTolerance
+	configs["Match"] = math.Abs(float64(difference)) <= allowed
+	return configs, nil
+}
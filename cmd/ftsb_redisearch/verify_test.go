@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseQueryIdKeepsTheSuffixesOfDisabledFeatures(t *testing.T) {
+	prevVerify, prevThinkTime := verify, thinkTime
+	defer func() {
+		verify, thinkTime = prevVerify, prevThinkTime
+	}()
+
+	verify, thinkTime = false, 0
+	queryId, exp, dependent, err := parseQueryId("Q1|count=2|dependent|a|b=c")
+	if err != nil || queryId != "Q1|count=2|dependent|a|b=c" || exp != nil || dependent {
+		t.Errorf("expected the whole column to be the query id, got %q ( expectation %v, dependent %v, error %v )", queryId, exp, dependent, err)
+	}
+
+	verify, thinkTime = true, time.Millisecond
+	queryId, exp, dependent, err = parseQueryId("Q1|count=2|a|top=doc1|dependent|b=c")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if queryId != "Q1|a|b=c" {
+		t.Errorf("expected the unknown suffixes to be kept on the query id, got %q", queryId)
+	}
+	if exp == nil || exp.count != 2 || exp.topDocId != "doc1" {
+		t.Errorf("expected count 2 and top doc1, got %+v", exp)
+	}
+	if !dependent {
+		t.Errorf("expected the row to be dependent")
+	}
+
+	if _, _, _, err = parseQueryId("Q1|count=two"); err == nil {
+		t.Errorf("expected a malformed expected count to be reported with -verify")
+	}
+}
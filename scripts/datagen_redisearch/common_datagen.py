@@ -1,6 +1,9 @@
 import gzip
 import os
+import random
 import shutil
+import socket
+import subprocess
 import tarfile
 import bz2
 import urllib.request
@@ -15,6 +18,156 @@ def remove_file_if_exists(filename):
         os.remove(filename)
 
 
+def get_generator_version():
+    """Returns the git sha1 of the generators checkout, suffixed with -dirty when it has uncommitted changes, or
+    unknown when it can't be determined (e.g. outside of a git checkout)"""
+    cwd = os.path.dirname(os.path.abspath(__file__))
+    try:
+        sha1 = subprocess.check_output(
+            ["git", "rev-parse", "HEAD"], cwd=cwd, stderr=subprocess.DEVNULL
+        )
+        changes = subprocess.check_output(
+            ["git", "status", "--porcelain", "--untracked-files=no"],
+            cwd=cwd,
+            stderr=subprocess.DEVNULL,
+        )
+    except (OSError, subprocess.CalledProcessError):
+        return "unknown"
+    version = sha1.decode().strip()
+    if changes.strip() != b"":
+        version = version + "-dirty"
+    return version
+
+
+def seed_random(args):
+    """Seeds the random generator with the --seed argument, printing it alongside the generator version and every
+    resolved argument, so that the generated files can be exactly reproduced later"""
+    print("Using random seed {0}".format(args.seed))
+    print("Generator version (git sha1): {0}".format(get_generator_version()))
+    print("Resolved arguments:")
+    for name, value in sorted(vars(args).items()):
+        print("\t--{0}: {1}".format(name.replace("_", "-"), value))
+    random.seed(args.seed)
+
+
+# the number of hash slots of a Redis cluster
+CLUSTER_SLOTS = 16384
+
+
+def crc16(data):
+    """CRC16 (XMODEM) checksum of data, as used by the Redis cluster key to slot mapping"""
+    crc = 0
+    for byte in data:
+        crc = crc ^ (byte << 8)
+        for _ in range(8):
+            if crc & 0x8000:
+                crc = ((crc << 1) ^ 0x1021) & 0xFFFF
+            else:
+                crc = (crc << 1) & 0xFFFF
+    return crc
+
+
+def key_hash_slot(key):
+    """Returns the cluster slot of key, hashing only its hash tag ( the first non empty {...} section ) if any"""
+    start = key.find("{")
+    if start >= 0:
+        end = key.find("}", start + 1)
+        if end > start + 1:
+            key = key[start + 1 : end]
+    return crc16(key.encode("utf-8")) % CLUSTER_SLOTS
+
+
+def get_slot_hash_tags(target_slots):
+    """Returns a hash tag for each of target_slots slots evenly spread across the cluster slots. Keys using the tags
+    round robin are evenly distributed across the shards of a cluster with its slots evenly split, as long as
+    target_slots is a multiple of the number of shards"""
+    slots = [n * CLUSTER_SLOTS // target_slots for n in range(target_slots)]
+    wanted = set(slots)
+    tags = {}
+    n = 0
+    while len(tags) < len(wanted):
+        tag = str(n)
+        slot = key_hash_slot(tag)
+        if slot in wanted and slot not in tags:
+            tags[slot] = tag
+        n = n + 1
+    return [tags[slot] for slot in slots]
+
+
+def encode_resp_command(cmd):
+    """Serializes a command (list of arguments) with the Redis protocol (RESP), as expected by redis-cli --pipe"""
+    encoded = ["*{}\r\n".format(len(cmd)).encode()]
+    for arg in cmd:
+        arg_bytes = str(arg).encode("utf-8")
+        encoded.append(b"$%d\r\n%s\r\n" % (len(arg_bytes), arg_bytes))
+    return b"".join(encoded)
+
+
+def read_resp_reply(f):
+    """Parses a Redis protocol (RESP2) reply from the file-like object f. Error replies raise a RuntimeError"""
+    line = f.readline()
+    if not line.endswith(b"\r\n"):
+        raise RuntimeError("connection closed while reading the reply")
+    kind, payload = line[:1], line[1:-2].decode("utf-8")
+    if kind == b"+":
+        return payload
+    if kind == b"-":
+        raise RuntimeError(payload)
+    if kind == b":":
+        return int(payload)
+    if kind == b"$":
+        if int(payload) < 0:
+            return None
+        return f.read(int(payload) + 2)[:-2].decode("utf-8")
+    if kind == b"*":
+        if int(payload) < 0:
+            return None
+        return [read_resp_reply(f) for _ in range(int(payload))]
+    raise RuntimeError("unexpected reply type {}".format(kind))
+
+
+def redis_command(host, port, password, cmd, timeout=10):
+    """Issues a single command (list of arguments) on a new connection, authenticating first if a password is
+    given, and returns its parsed reply"""
+    with socket.create_connection((host, port), timeout=timeout) as conn:
+        f = conn.makefile("rb")
+        if password is not None and password != "":
+            conn.sendall(encode_resp_command(["AUTH", password]))
+            read_resp_reply(f)
+        conn.sendall(encode_resp_command(cmd))
+        return read_resp_reply(f)
+
+
+class SyncedOutputFile:
+    """Output file flushed and fsynced to disk every sync_every writes (0 = only when closed), so that the commands
+    written so far are recoverable if a long generation is interrupted. buffer_size is the write buffer size in
+    bytes (-1 for the default). Each csv.writer row is a single write"""
+
+    def __init__(self, filename, mode="w", buffer_size=-1, sync_every=0):
+        if "b" in mode:
+            self.file = open(filename, mode, buffering=buffer_size)
+        else:
+            self.file = open(filename, mode, buffering=buffer_size, newline="")
+        self.sync_every = sync_every
+        self.writes = 0
+
+    def write(self, data):
+        written = self.file.write(data)
+        self.writes = self.writes + 1
+        if self.sync_every > 0 and self.writes % self.sync_every == 0:
+            self.sync()
+        return written
+
+    def sync(self):
+        self.file.flush()
+        os.fsync(self.file.fileno())
+
+    def close(self):
+        if self.sync_every > 0:
+            self.sync()
+        self.file.close()
+
+
 def decompress_file(filename):
     splitted = os.path.splitext(filename)
     stripped_fname = splitted[0]
@@ -130,6 +283,7 @@ def generate_setup_json(
 ):
     setup_json = {
         "specifications-version": json_version,
+        "generator-version": get_generator_version(),
         "project": project,
         "name": test_name,
         "description": description,
@@ -26,6 +26,7 @@ from common_datagen import (
     add_deployment_requirements_utilities,
     init_deployment_requirement,
     remove_file_if_exists,
+    seed_random,
 )
 
 
@@ -717,8 +718,7 @@ if __name__ == "__main__":
             len(countries_alpha_3), " ".join(countries_p_str)
         )
     )
-    print("Using random seed {0}".format(args.seed))
-    random.seed(args.seed)
+    seed_random(args)
 
     generate_setup_commands()
     print("\t saving to {} and {}".format(setup_fname, all_fname))
@@ -23,6 +23,7 @@ field_tokenization = ",.<>{}[]\"':;!@#$%^&*()-+=~"
 SIMPLE_WORD_QUERY = "simple-1word-query"
 SIMPLE_2WORD_UNION_QUERY = "2word-union-query"
 SIMPLE_2WORD_INT_QUERY = "2word-intersection-query"
+TWO_FIELD_2WORD_INT_QUERY = "2field-2word-intersection-query"
 WILDCARD_QUERY = "wildcard"
 SUFFIX_QUERY = "suffix"
 CONTAINS_QUERY = "contains"
@@ -43,6 +44,7 @@ from common_datagen import (
     init_deployment_requirement,
     remove_file_if_exists,
     decompress_file,
+    seed_random,
 )
 
 from tqdm import tqdm
@@ -60,7 +62,7 @@ def generate_enwiki_abstract_index_type():
     return types
 
 
-def generate_ft_create_row(index, index_types, use_ftadd):
+def generate_ft_create_row(index, index_types, use_ftadd, with_suffix_trie=False):
     if use_ftadd:
         cmd = ['"FT.CREATE"', '"{index}"'.format(index=index), '"SCHEMA"']
     else:
@@ -74,6 +76,8 @@ def generate_ft_create_row(index, index_types, use_ftadd):
     for f, v in index_types.items():
         cmd.append('"{}"'.format(f))
         cmd.append('"{}"'.format(v))
+        if with_suffix_trie:
+            cmd.append('"WITHSUFFIXTRIE"')
         cmd.append('"SORTABLE"')
     return cmd
 
@@ -110,8 +114,8 @@ def use_case_to_cmd(use_ftadd, title, url, abstract, total_docs):
     return cmd
 
 
-def getQueryWords(doc, stop_words, size):
-    words = doc["abstract"]
+def getQueryWords(doc, stop_words, size, field="abstract"):
+    words = doc[field]
     words = re.sub("[^0-9a-zA-Z]+", " ", words)
     words = words.split(" ")
     queryWords = []
@@ -127,6 +131,27 @@ def getQueryWords(doc, stop_words, size):
     return queryWords, totalQueryWords
 
 
+def count_doc_terms(doc_freqs, doc, stop_words):
+    """Accounts the distinct terms of a document that can be picked as query words (see getQueryWords) on the
+    term -> document frequency table. Terms are lowercased, given the search is case insensitive"""
+    terms = set()
+    for field in ["title", "abstract"]:
+        words = re.sub("[^0-9a-zA-Z]+", " ", doc[field]).split(" ")
+        for word in words:
+            if len(word) > 3 and word not in stop_words and word != "Wikipedia":
+                terms.add(word.lower())
+    for term in terms:
+        doc_freqs[term] = doc_freqs.get(term, 0) + 1
+
+
+def get_terms_by_doc_freq(doc_freqs, target, tolerance):
+    """Returns the terms whose document frequency is within tolerance (ratio) of target, sorted so that the
+    generated queries are deterministic for a given seed"""
+    low = target * (1.0 - tolerance)
+    high = target * (1.0 + tolerance)
+    return sorted([term for term, freq in doc_freqs.items() if low <= freq <= high])
+
+
 def generate_benchmark_commands(
     total_benchmark_commands,
     bench_fname,
@@ -136,6 +161,8 @@ def generate_benchmark_commands(
     stop_words,
     search_no_content,
     query_choices,
+    query_caps={},
+    target_terms=None,
 ):
     all_csvfile = open(all_fname, "a", newline="")
     bench_csvfile = open(bench_fname, "w", newline="")
@@ -144,11 +171,27 @@ def generate_benchmark_commands(
     progress = tqdm(unit="docs", total=total_benchmark_commands)
     total_docs = len(docs)
     generated_commands = 0
+    generated_per_type = {choice: 0 for choice in query_choices}
+    available_choices = list(query_choices)
     while generated_commands < total_benchmark_commands:
+        # stop emitting a query type once its cap is hit, while continuing the others
+        available_choices = [
+            choice
+            for choice in available_choices
+            if choice not in query_caps
+            or generated_per_type[choice] < query_caps[choice]
+        ]
+        if len(available_choices) == 0:
+            print(
+                "All query types reached their cap. Generated {} commands".format(
+                    generated_commands
+                )
+            )
+            break
         random_doc_pos = random.randint(0, total_docs - 1)
         doc = docs[random_doc_pos]
         words, totalW = getQueryWords(doc, stop_words, 2)
-        choice = random.choices(query_choices)[0]
+        choice = random.choices(available_choices)[0]
         if len(words) < 1:
             continue
         term = words[0]
@@ -156,7 +199,14 @@ def generate_benchmark_commands(
         prefix_min = 3
         prefix_max = 3
         generated_row = None
-        if choice == SIMPLE_WORD_QUERY and len(words) >= 1:
+        if choice == SIMPLE_WORD_QUERY and target_terms is not None:
+            generated_row = generate_ft_search_row(
+                indexname,
+                SIMPLE_WORD_QUERY,
+                random.choice(target_terms[SIMPLE_WORD_QUERY]),
+                search_no_content,
+            )
+        elif choice == SIMPLE_WORD_QUERY and len(words) >= 1:
             generated_row = generate_ft_search_row(
                 indexname, SIMPLE_WORD_QUERY, words[0], search_no_content
             )
@@ -203,6 +253,15 @@ def generate_benchmark_commands(
                 "{} {}".format(words[0], words[1]),
                 search_no_content,
             )
+        elif choice == SIMPLE_2WORD_INT_QUERY and target_terms is not None:
+            # the union of two terms of about half the target frequency each
+            union_terms = random.sample(target_terms[SIMPLE_2WORD_INT_QUERY], 2)
+            generated_row = generate_ft_search_row(
+                indexname,
+                SIMPLE_2WORD_INT_QUERY,
+                "{}|{}".format(union_terms[0], union_terms[1]),
+                search_no_content,
+            )
         elif choice == SIMPLE_2WORD_INT_QUERY and len(words) >= 2:
             generated_row = generate_ft_search_row(
                 indexname,
@@ -210,14 +269,41 @@ def generate_benchmark_commands(
                 "{}|{}".format(words[0], words[1]),
                 search_no_content,
             )
+        elif choice == TWO_FIELD_2WORD_INT_QUERY:
+            title_words, _ = getQueryWords(doc, stop_words, 1, "title")
+            if len(title_words) >= 1:
+                generated_row = generate_ft_search_row(
+                    indexname,
+                    TWO_FIELD_2WORD_INT_QUERY,
+                    "@title:{} @abstract:{}".format(title_words[0], words[0]),
+                    search_no_content,
+                )
         if generated_row != None:
             all_csv_writer.writerow(generated_row)
             bench_csv_writer.writerow(generated_row)
             progress.update()
             generated_commands = generated_commands + 1
+            generated_per_type[choice] = generated_per_type[choice] + 1
     progress.close()
     bench_csvfile.close()
     all_csvfile.close()
+    return generated_commands
+
+
+def parse_query_caps(query_caps_str, query_choices):
+    query_caps = {}
+    for cap in query_caps_str.split(","):
+        if cap == "":
+            continue
+        query_type, _, count = cap.partition("=")
+        if query_type not in query_choices or not count.isdigit():
+            raise ValueError(
+                "invalid query cap {}. Expected <query type>=<count>, for one of the --query-choices".format(
+                    cap
+                )
+            )
+        query_caps[query_type] = int(count)
+    return query_caps
 
 
 def generate_wildcard_row(
@@ -369,6 +455,7 @@ if __name__ == "__main__":
                 SIMPLE_WORD_QUERY,
                 SIMPLE_2WORD_UNION_QUERY,
                 SIMPLE_2WORD_INT_QUERY,
+                TWO_FIELD_2WORD_INT_QUERY,
                 PREFIX_QUERY,
                 SUFFIX_QUERY,
                 CONTAINS_QUERY,
@@ -376,6 +463,26 @@ if __name__ == "__main__":
             ]
         ),
     )
+    parser.add_argument(
+        "--query-caps",
+        type=str,
+        default="",
+        help="comma separated list of <query type>=<count> caps on the number of queries generated per type (e.g. 2word-union-query=1000000,2word-intersection-query=500000). Once a type hits its cap only the remaining types are generated. Types without a cap are only limited by --total-benchmark-commands",
+    )
+    parser.add_argument(
+        "--target-result-size",
+        type=int,
+        default=0,
+        help="generate the {} and {} (term1|term2) queries from terms of known document frequency, so that each query matches approximately this number of the setup documents. The other query types are not affected. If 0, the query words are randomly picked from the documents".format(
+            SIMPLE_WORD_QUERY, SIMPLE_2WORD_INT_QUERY
+        ),
+    )
+    parser.add_argument(
+        "--target-result-size-tolerance",
+        type=float,
+        default=0.1,
+        help="maximum relative deviation from --target-result-size of the document frequency of the picked terms",
+    )
     parser.add_argument(
         "--upload-artifacts-s3-uncompressed",
         action="store_true",
@@ -394,6 +501,14 @@ if __name__ == "__main__":
         action="store_true",
         help="Use FT.ADD instead of HSET",
     )
+    parser.add_argument(
+        "--with-suffix-trie",
+        default=False,
+        action="store_true",
+        help="create the TEXT fields WITHSUFFIXTRIE, trading memory for faster {} and {} queries".format(
+            SUFFIX_QUERY, CONTAINS_QUERY
+        ),
+    )
     parser.add_argument(
         "--search-no-content",
         default=False,
@@ -421,6 +536,15 @@ if __name__ == "__main__":
     test_name = args.test_name
     search_no_content = args.search_no_content
     query_choices = args.query_choices.split(",")
+    query_caps = parse_query_caps(args.query_caps, query_choices)
+    target_result_size = args.target_result_size
+    if target_result_size < 0:
+        raise ValueError("--target-result-size can't be negative")
+    if (
+        args.target_result_size_tolerance < 0.0
+        or args.target_result_size_tolerance > 1.0
+    ):
+        raise ValueError("--target-result-size-tolerance must be within [0,1]")
     if search_no_content:
         test_name += "-search-no-content"
     description = args.test_description
@@ -531,14 +655,15 @@ if __name__ == "__main__":
 
     total_docs = 0
 
-    print("Using random seed {0}".format(args.seed))
-    random.seed(args.seed)
+    seed_random(args)
 
     print("Using the following stop-words: {0}".format(stop_words))
 
     index_types = generate_enwiki_abstract_index_type()
     print("-- generating the ft.create commands -- ")
-    ft_create_cmd = generate_ft_create_row(indexname, index_types, use_ftadd)
+    ft_create_cmd = generate_ft_create_row(
+        indexname, index_types, use_ftadd, args.with_suffix_trie
+    )
     print("FT.CREATE command: {}".format(" ".join(ft_create_cmd)))
     setup_commands.append(ft_create_cmd)
 
@@ -594,6 +719,8 @@ if __name__ == "__main__":
     total_docs = 0
     if doc_limit == 0:
         doc_limit = len(docs)
+    # term -> number of setup documents containing it, to pick the terms matching the target result size
+    doc_freqs = {}
     while total_docs < doc_limit:
         total_docs = total_docs + 1
         random_doc_pos = random.randint(0, len(docs) - 1)
@@ -604,18 +731,46 @@ if __name__ == "__main__":
         progress.update()
         setup_csv_writer.writerow(cmd)
         all_csv_writer.writerow(cmd)
+        if target_result_size > 0:
+            count_doc_terms(doc_freqs, doc, stop_words)
 
     progress.close()
     all_csvfile.close()
     setup_csvfile.close()
 
+    target_terms = None
+    if target_result_size > 0:
+        tolerance = args.target_result_size_tolerance
+        target_terms = {
+            SIMPLE_WORD_QUERY: get_terms_by_doc_freq(
+                doc_freqs, target_result_size, tolerance
+            ),
+            SIMPLE_2WORD_INT_QUERY: get_terms_by_doc_freq(
+                doc_freqs, target_result_size / 2.0, tolerance
+            ),
+        }
+        for choice, min_terms in [(SIMPLE_WORD_QUERY, 1), (SIMPLE_2WORD_INT_QUERY, 2)]:
+            if choice not in query_choices:
+                continue
+            if len(target_terms[choice]) < min_terms:
+                raise ValueError(
+                    "not enough terms with a document frequency close to the target result size of {} for {}. Consider increasing --target-result-size-tolerance".format(
+                        target_result_size, choice
+                    )
+                )
+            print(
+                "Using {} terms matching approximately {} documents for {}".format(
+                    len(target_terms[choice]), target_result_size, choice
+                )
+            )
+
     print(
         "-- generating {} full text search commands -- ".format(
             total_benchmark_commands
         )
     )
     print("\t saving to {} and {}".format(bench_fname, all_fname))
-    generate_benchmark_commands(
+    total_benchmark_commands = generate_benchmark_commands(
         total_benchmark_commands,
         bench_fname,
         all_fname,
@@ -624,6 +779,8 @@ if __name__ == "__main__":
         stop_words,
         search_no_content,
         query_choices,
+        query_caps,
+        target_terms,
     )
 
     total_commands = total_docs
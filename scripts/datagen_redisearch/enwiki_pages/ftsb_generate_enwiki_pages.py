@@ -40,6 +40,7 @@ from common_datagen import (
     init_deployment_requirement,
     remove_file_if_exists,
     decompress_file,
+    seed_random,
 )
 
 from tqdm import tqdm
@@ -531,8 +532,9 @@ if __name__ == "__main__":
 
     total_docs = 0
 
-    print("Using random seed {0}".format(args.seed))
-    random.seed(args.seed)
+    seed_random(args)
+    # the page sizes are drawn with numpy
+    np.random.seed(args.seed)
 
     print("Using the following stop-words: {0}".format(stop_words))
 
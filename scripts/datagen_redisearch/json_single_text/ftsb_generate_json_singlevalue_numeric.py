@@ -29,6 +29,7 @@ from common_datagen import (
     add_deployment_requirements_utilities,
     init_deployment_requirement,
     remove_file_if_exists,
+    seed_random,
 )
 from pathlib import Path
 import string
@@ -260,8 +261,7 @@ if __name__ == "__main__":
 
     total_docs = 0
 
-    print("Using random seed {0}".format(args.seed))
-    random.seed(args.seed)
+    seed_random(args)
 
     total_docs = 0
     doc_ids = []
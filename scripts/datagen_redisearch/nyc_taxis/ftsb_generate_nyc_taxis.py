@@ -29,6 +29,7 @@ from common_datagen import (
     add_deployment_requirements_utilities,
     init_deployment_requirement,
     remove_file_if_exists,
+    seed_random,
 )
 from pathlib import Path
 
@@ -413,8 +414,7 @@ if __name__ == "__main__":
 
     total_docs = 0
 
-    print("Using random seed {0}".format(args.seed))
-    random.seed(args.seed)
+    seed_random(args)
 
     index_types = generate_nyc_taxis_index_type()
     print("-- generating the ft.create commands -- ")
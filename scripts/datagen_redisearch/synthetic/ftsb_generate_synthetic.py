@@ -0,0 +1,2553 @@
+#!/usr/bin/python3
+
+import argparse
+import csv
+import json
+import math
+import os
+import random
+import re
+import string
+
+# package local imports
+import sys
+
+from tqdm import tqdm
+
+sys.path.append(os.getcwd() + "/..")
+
+from common_datagen import (
+    generate_setup_json,
+    humanized_bytes,
+    del_non_use_case_specific_keys,
+    encode_resp_command,
+    redis_command,
+    add_key_metric,
+    upload_dataset_artifacts_s3,
+    add_deployment_requirements_redis_server_module,
+    add_deployment_requirements_benchmark_tool,
+    add_deployment_requirements_utilities,
+    init_deployment_requirement,
+    remove_file_if_exists,
+    SyncedOutputFile,
+    seed_random,
+    get_slot_hash_tags,
+    CLUSTER_SLOTS,
+)
+
+NUMERIC = "NUMERIC"
+TEXT = "TEXT"
+TAG = "TAG"
+GEOSHAPE = "GEOSHAPE"
+
+SEARCH_NUMERIC_RANGE = "numeric-range-query"
+SIMPLE_WORD_QUERY = "simple-1word-query"
+SORTBY_QUERY = "sortby-query"
+HYBRID_QUERY = "hybrid-query"
+SUGGET_QUERY = "sugget-query"
+GEOSHAPE_QUERY = "geoshape-query"
+SUFFIX_QUERY = "suffix-query"
+CONTAINS_QUERY = "contains-query"
+BOOLEAN_QUERY = "boolean-query"
+ISMISSING_QUERY = "ismissing-query"
+APPLY_FILTER_QUERY = "apply-filter-query"
+RECENT_WINDOW_QUERY = "recent-window-query"
+INFIELDS_QUERY = "infields-query"
+RANGE_SORTBY_QUERY = "range-sortby-query"
+TAG_NEGATION_QUERY = "tag-negation-query"
+# injected at --stopword-query-rate rather than picked from the query choices
+STOPWORD_QUERY = "stopword-only-query"
+# the default stopwords list of RediSearch
+DEFAULT_STOPWORDS = [
+    "a",
+    "is",
+    "the",
+    "an",
+    "and",
+    "are",
+    "as",
+    "at",
+    "be",
+    "but",
+    "by",
+    "for",
+    "if",
+    "in",
+    "into",
+    "it",
+    "no",
+    "not",
+    "of",
+    "on",
+    "or",
+    "such",
+    "that",
+    "their",
+    "then",
+    "there",
+    "these",
+    "they",
+    "this",
+    "to",
+    "was",
+    "will",
+    "with",
+]
+SUGADD = "sugadd"
+# the filler field used to pad the documents up to --min-doc-bytes
+PADDING_FIELD = "padding"
+ALTER = "alter"
+choices_str = ",".join(
+    [
+        SEARCH_NUMERIC_RANGE,
+        SIMPLE_WORD_QUERY,
+        SORTBY_QUERY,
+        HYBRID_QUERY,
+        SUGGET_QUERY,
+        GEOSHAPE_QUERY,
+        SUFFIX_QUERY,
+        CONTAINS_QUERY,
+        BOOLEAN_QUERY,
+        ISMISSING_QUERY,
+        APPLY_FILTER_QUERY,
+        RECENT_WINDOW_QUERY,
+        INFIELDS_QUERY,
+        RANGE_SORTBY_QUERY,
+        TAG_NEGATION_QUERY,
+    ]
+)
+
+scorers = [
+    "TFIDF",
+    "TFIDF.DOCNORM",
+    "BM25",
+    "BM25STD",
+    "DISMAX",
+    "DOCSCORE",
+    "HAMMING",
+]
+
+CONSTANT_DISTRIBUTION = "constant"
+UNIFORM_DISTRIBUTION = "uniform"
+LOGNORMAL_DISTRIBUTION = "lognormal"
+distributions_str = ",".join(
+    [CONSTANT_DISTRIBUTION, UNIFORM_DISTRIBUTION, LOGNORMAL_DISTRIBUTION]
+)
+
+UNIFORM_ACCESS = "uniform"
+RECENCY_ACCESS = "recency"
+# on the recency biased access, the offset from the most recent id follows an exponential distribution with
+# mean (1 / RECENCY_ACCESS_LAMBDA) of the id space, i.e. half of the accesses land on the most recent ~7% ids
+RECENCY_ACCESS_LAMBDA = 10.0
+id_access_str = ",".join([UNIFORM_ACCESS, RECENCY_ACCESS])
+
+FLAT = "FLAT"
+SPHERICAL = "SPHERICAL"
+# the area where the geometries are placed, per coordinate system. SPHERICAL coordinates are longitude latitude
+geoshape_extents = {
+    FLAT: {"min_x": 0.0, "max_x": 1000.0, "min_y": 0.0, "max_y": 1000.0},
+    SPHERICAL: {"min_x": -180.0, "max_x": 180.0, "min_y": -85.0, "max_y": 85.0},
+}
+
+size_units = {"": 1, "B": 1, "K": 1 << 10, "M": 1 << 20, "G": 1 << 30, "T": 1 << 40}
+
+
+def parse_size(size_str):
+    """Parses a human readable size (e.g. 512M, 10GB) into bytes"""
+    match = re.match(r"^\s*(\d+)\s*([KMGT]?)B?\s*$", size_str.upper())
+    if match is None:
+        raise ValueError("invalid size: {}".format(size_str))
+    return int(match.group(1)) * size_units[match.group(2)]
+
+
+def human_format(num):
+    magnitude = 0
+    while abs(num) >= 1000:
+        magnitude += 1
+        num /= 1000.0
+    # add more suffixes if you need them
+    return "%.0f%s" % (num, ["", "K", "M", "G", "T", "P"][magnitude])
+
+
+def rand_str(minN, maxN):
+    return "".join(
+        random.choices(string.ascii_lowercase, k=random.randint(minN, maxN))
+    )
+
+
+def generate_vocabulary(dictionary_file, vocab_size):
+    """Returns vocab_size distinct words, either read from the dictionary file (one word per line) or randomly generated"""
+    vocabulary = []
+    if dictionary_file != "":
+        seen = set()
+        with open(dictionary_file) as dictf:
+            for line in dictf:
+                word = line.strip()
+                if word == "" or word in seen:
+                    continue
+                seen.add(word)
+                vocabulary.append(word)
+                if len(vocabulary) >= vocab_size:
+                    break
+    else:
+        seen = set()
+        while len(vocabulary) < vocab_size:
+            word = rand_str(3, 10)
+            if word not in seen:
+                seen.add(word)
+                vocabulary.append(word)
+    return vocabulary
+
+
+def parse_text_field_weights(weights_str):
+    """Parses a comma separated list of field=weight pairs (e.g. text1=2.0,text2=0.5)"""
+    weights = {}
+    for pair in [p for p in weights_str.split(",") if p.strip() != ""]:
+        kv = pair.split("=")
+        if len(kv) != 2:
+            raise ValueError(
+                "malformed text field weight {}. Expected field=weight".format(pair)
+            )
+        weight = float(kv[1])
+        if weight <= 0.0:
+            raise ValueError("the weight of field {} must be positive".format(kv[0]))
+        weights[kv[0].strip()] = weight
+    return weights
+
+
+def parse_field_cardinalities(cardinalities_str, numeric_fields):
+    """Parses a comma separated list of cardinalities, one per NUMERIC field (e.g. 2,100,1000000)"""
+    cardinalities = [int(c) for c in cardinalities_str.split(",") if c.strip() != ""]
+    if len(cardinalities) > numeric_fields:
+        raise ValueError(
+            "got {} field cardinalities for {} NUMERIC fields".format(
+                len(cardinalities), numeric_fields
+            )
+        )
+    for c in cardinalities:
+        if c < 1:
+            raise ValueError("field cardinalities must be positive")
+    return cardinalities
+
+
+def parse_field_correlations(correlations_file, schema):
+    """Reads the --field-correlations lookup table: a JSON object mapping each correlated field to the field it
+    depends on and, per value of the latter, the candidate values of the correlated field, e.g.
+    {"tag2": {"depends_on": "tag1", "values": {"tag1": ["tag1", "tag2"], "tag2": ["tag3"]}}}. The correlated
+    fields are generated in the file order, so a field may depend on a previous correlated field"""
+    with open(correlations_file) as f:
+        correlations = json.load(f)
+    if not isinstance(correlations, dict):
+        raise ValueError("--field-correlations expects a JSON object")
+    correlated = set()
+    for field, correlation in correlations.items():
+        if field not in schema or schema[field]["type"] not in [NUMERIC, TEXT, TAG]:
+            raise ValueError(
+                "correlated field {} is not a NUMERIC, TEXT or TAG field of the schema".format(
+                    field
+                )
+            )
+        source = correlation.get("depends_on")
+        if source not in schema or source == field:
+            raise ValueError(
+                "correlated field {} depends on {}, which is not another field of the schema".format(
+                    field, source
+                )
+            )
+        if source in correlations and source not in correlated:
+            raise ValueError(
+                "correlated field {} depends on {}, which is correlated after it".format(
+                    field, source
+                )
+            )
+        values = correlation.get("values")
+        if not isinstance(values, dict) or any(
+            not isinstance(v, list) or len(v) == 0 for v in values.values()
+        ):
+            raise ValueError(
+                "the values of correlated field {} have to map each {} value to a non empty list".format(
+                    field, source
+                )
+            )
+        correlated.add(field)
+    return correlations
+
+
+def correlate_doc(doc, correlations):
+    """Replaces the value of each correlated field by a random candidate for the value of the field it depends on.
+    The fields without candidates for that value keep their independent value"""
+    for field, correlation in correlations.items():
+        candidates = correlation["values"].get(str(doc[correlation["depends_on"]]))
+        if candidates is not None:
+            doc[field] = random.choice(candidates)
+
+
+def generate_synthetic_schema(
+    numeric_fields,
+    text_fields,
+    sortable_fields,
+    text_field_weights={},
+    tag_fields=0,
+    geoshape_fields=0,
+    geoshape_coord_system=FLAT,
+    with_suffix_trie=False,
+    index_missing=False,
+):
+    schema = {}
+    for n in range(1, numeric_fields + 1):
+        schema["numeric{}".format(n)] = {"type": NUMERIC, "field_options": []}
+    for n in range(1, text_fields + 1):
+        schema["text{}".format(n)] = {"type": TEXT, "field_options": []}
+        if with_suffix_trie:
+            schema["text{}".format(n)]["field_options"].append("WITHSUFFIXTRIE")
+    for n in range(1, tag_fields + 1):
+        schema["tag{}".format(n)] = {"type": TAG, "field_options": []}
+    for n in range(1, geoshape_fields + 1):
+        schema["geom{}".format(n)] = {
+            "type": GEOSHAPE,
+            "field_options": [geoshape_coord_system],
+        }
+    for f, weight in text_field_weights.items():
+        if f not in schema or schema[f]["type"] != TEXT:
+            raise ValueError(
+                "weighted field {} is not a TEXT field of the schema".format(f)
+            )
+        # the default weight is 1.0, so there is no need to set it explicitly
+        if weight != 1.0:
+            schema[f]["field_options"].extend(["WEIGHT", str(weight)])
+    if index_missing:
+        # index the documents missing each field, so that they can be matched by ismissing(@field)
+        for f in schema:
+            schema[f]["field_options"].append("INDEXMISSING")
+    for f in sortable_fields:
+        if f not in schema:
+            raise ValueError("sortable field {} is not part of the schema".format(f))
+        schema[f]["field_options"].append("SORTABLE")
+    return schema
+
+
+def get_sortable_fields(schema):
+    return [f for f, v in schema.items() if "SORTABLE" in v["field_options"]]
+
+
+def get_sortable_numeric_fields(schema):
+    return [f for f in get_sortable_fields(schema) if schema[f]["type"] == NUMERIC]
+
+
+def get_indexmissing_fields(schema):
+    return [f for f, v in schema.items() if "INDEXMISSING" in v["field_options"]]
+
+
+def parse_ft_info_schema(info):
+    """Parses the fields of an FT.INFO reply into a schema. RediSearch 2.x lists them as "attributes" (identifier,
+    attribute, type, options...) while older versions list them as "fields" (name, type, options...). The field
+    types the generator has no queries for (like VECTOR or GEO) are skipped"""
+    info = dict(zip(info[::2], info[1::2]))
+    fields = info.get("attributes")
+    if fields is None:
+        fields = [[f[0], "attribute", f[0]] + f[1:] for f in info.get("fields", [])]
+    schema = {}
+    for field in fields:
+        tokens = [str(t) for t in field]
+        options = dict(zip(tokens[:-1], tokens[1:]))
+        name = options.get("attribute", options.get("identifier"))
+        field_type = options.get("type", "").upper()
+        if field_type not in [NUMERIC, TEXT, TAG, GEOSHAPE]:
+            print("skipping the {} field {} of the index".format(field_type, name))
+            continue
+        field_options = []
+        if field_type == GEOSHAPE:
+            field_options.append(options.get("coord_system", FLAT).upper())
+        for flag in ["WITHSUFFIXTRIE", "INDEXMISSING", "SORTABLE"]:
+            if flag in tokens:
+                field_options.append(flag)
+        schema[name] = {"type": field_type, "field_options": field_options}
+    return schema
+
+
+def get_index_schema(address, password, index):
+    """Discovers the schema of an existing index via FT.INFO, given the host:port of its server"""
+    host, _, port = address.rpartition(":")
+    if host == "" or not port.isdigit():
+        raise ValueError(
+            "--schema-from-index must be host:port. Got {}".format(address)
+        )
+    info = redis_command(host, int(port), password, ["FT.INFO", index])
+    return parse_ft_info_schema(info)
+
+
+def generate_ft_create_row(index, schema, use_hset, doc_prefix):
+    if use_hset:
+        cmd = [
+            "FT.CREATE",
+            "{index}".format(index=index),
+            "ON",
+            "HASH",
+            "PREFIX",
+            "1",
+            doc_prefix,
+            "SCHEMA",
+        ]
+    else:
+        cmd = ["FT.CREATE", "{index}".format(index=index), "SCHEMA"]
+    for f, v in schema.items():
+        cmd.append(f)
+        cmd.append(v["type"])
+        if len(v["field_options"]) > 0:
+            cmd.extend(v["field_options"])
+    return cmd
+
+
+def format_doc_id(doc_prefix, n, slot_tags=[]):
+    """Returns the key of the n-th document. Given slot_tags, the documents are assigned round robin to their hash
+    tags ( e.g. doc:{12}345 ), so that the keys are evenly spread across the cluster slots"""
+    if len(slot_tags) > 0:
+        return "{}{{{}}}{}".format(doc_prefix, slot_tags[n % len(slot_tags)], n)
+    return "{}{}".format(doc_prefix, n)
+
+
+def generate_ft_drop_row(index):
+    cmd = ["FT.DROP", "{index}".format(index=index), "DD"]
+    return cmd
+
+
+def get_doc_words(words_per_doc, distribution, sigma):
+    """Returns the number of words of a document, following the requested size distribution with mean words_per_doc"""
+    if distribution == UNIFORM_DISTRIBUTION:
+        return random.randint(1, 2 * words_per_doc - 1)
+    elif distribution == LOGNORMAL_DISTRIBUTION:
+        # a few huge documents and many small ones. mu is chosen so that the mean is kept at words_per_doc
+        mu = math.log(words_per_doc) - (sigma * sigma) / 2.0
+        return max(1, int(round(random.lognormvariate(mu, sigma))))
+    return words_per_doc
+
+
+def generate_numeric_value(numeric_range, low=None):
+    """Returns a random NUMERIC value within [low, numeric_range max]. low defaults to the range min.
+    Values are integers unless the range has a decimal precision"""
+    if low is None:
+        low = numeric_range["min"]
+    if numeric_range["precision"] > 0:
+        return round(
+            random.uniform(low, numeric_range["max"]), numeric_range["precision"]
+        )
+    return random.randint(low, numeric_range["max"])
+
+
+def get_field_numeric_range(numeric_range, field):
+    """Returns the NUMERIC range of a field, narrowed when the field has its own cardinality"""
+    field_max = numeric_range["field_max"].get(field)
+    if field_max is None:
+        return numeric_range
+    return {
+        "min": numeric_range["min"],
+        "max": field_max,
+        "precision": numeric_range["precision"],
+        "field_max": {},
+    }
+
+
+def generate_wkt_point(extent):
+    return "POINT({} {})".format(
+        round(random.uniform(extent["min_x"], extent["max_x"]), 6),
+        round(random.uniform(extent["min_y"], extent["max_y"]), 6),
+    )
+
+
+def generate_wkt_polygon(extent, vertices, radius):
+    """Returns a star-shaped ( and therefore simple ) polygon with the given number of vertices, each at a random
+    distance between radius/2 and radius from a random center, ordered clockwise and closed on its first vertex"""
+    center_x = random.uniform(extent["min_x"] + radius, extent["max_x"] - radius)
+    center_y = random.uniform(extent["min_y"] + radius, extent["max_y"] - radius)
+    angles = sorted(
+        [random.uniform(0, 2 * math.pi) for _ in range(vertices)], reverse=True
+    )
+    points = []
+    for angle in angles:
+        distance = random.uniform(radius / 2.0, radius)
+        points.append(
+            "{} {}".format(
+                round(center_x + distance * math.cos(angle), 6),
+                round(center_y + distance * math.sin(angle), 6),
+            )
+        )
+    points.append(points[0])
+    return "POLYGON(({}))".format(", ".join(points))
+
+
+def generate_geometry(geoshape_options):
+    """Returns a WKT point or polygon, following the --geoshape-* options"""
+    if random.random() < geoshape_options["point_probability"]:
+        return generate_wkt_point(geoshape_options["extent"])
+    return generate_wkt_polygon(
+        geoshape_options["extent"],
+        geoshape_options["vertices"],
+        geoshape_options["radius"],
+    )
+
+
+def generate_doc(
+    schema,
+    numeric_range,
+    vocabulary,
+    words_per_doc,
+    tag_values=[],
+    geoshape_options=None,
+    shuffle_fields=False,
+    missing_rate=0.0,
+    correlations={},
+):
+    doc = {}
+    text_fields = [f for f, v in schema.items() if v["type"] == TEXT]
+    for f, v in schema.items():
+        if v["type"] == NUMERIC:
+            doc[f] = generate_numeric_value(
+                get_field_numeric_range(numeric_range, f)
+            )
+        elif v["type"] == TAG:
+            doc[f] = random.choice(tag_values)
+        elif v["type"] == GEOSHAPE:
+            doc[f] = generate_geometry(geoshape_options)
+        elif v["type"] == TEXT:
+            # spread the document words evenly across the text fields
+            words = words_per_doc // len(text_fields)
+            if f == text_fields[0]:
+                words = words + words_per_doc % len(text_fields)
+            doc[f] = " ".join(random.choices(vocabulary, k=words))
+    correlate_doc(doc, correlations)
+    if missing_rate > 0.0:
+        # omit each field with missing_rate, keeping at least one so that the document is never empty
+        present = [f for f in doc if random.random() >= missing_rate]
+        if len(present) == 0:
+            present = [random.choice(list(doc))]
+        doc = {f: v for f, v in doc.items() if f in present}
+    if shuffle_fields:
+        # emit the fields on a random order, so that the benchmark doesn't rely on a fixed fields order
+        fields = list(doc.items())
+        random.shuffle(fields)
+        doc = dict(fields)
+    return doc
+
+
+def estimate_doc_size(doc_id, doc):
+    """Estimates the encoded size of a document as the sum of its id, field names and values"""
+    size = len(doc_id)
+    for f, v in doc.items():
+        size = size + len(f) + len(str(v))
+    return size
+
+
+def get_doc_timestamp(n, timestamp_options):
+    """Returns the timestamp of the n-th document: the documents are spaced by the timestamp interval, plus a
+    random jitter smaller than the interval, so that the timestamps keep increasing with the document number"""
+    value = timestamp_options["start"] + n * timestamp_options["interval"]
+    if timestamp_options["jitter"] > 0:
+        value = value + random.uniform(0, timestamp_options["jitter"])
+    return round_timestamp(value)
+
+
+def add_doc_timestamp(doc, n, timestamp_options):
+    """Sets the timestamp of the n-th document, when the documents have a timestamp field"""
+    if timestamp_options is not None:
+        doc[timestamp_options["field"]] = get_doc_timestamp(n, timestamp_options)
+
+
+def round_timestamp(value):
+    """Rounds a timestamp to milliseconds, dropping the decimal part of whole timestamps"""
+    value = round(value, 3)
+    if value == int(value):
+        return int(value)
+    return value
+
+
+def generate_recent_window_row(index, latest_n, timestamp_options, search_options):
+    """Composes a range query over the timestamps of the last window, relative to the latest document written so
+    far, as an events dashboard querying the most recent events would"""
+    latest = (
+        timestamp_options["start"]
+        + latest_n * timestamp_options["interval"]
+        + timestamp_options["jitter"]
+    )
+    params = new_query_params(search_options)
+    cmd = [
+        "READ",
+        RECENT_WINDOW_QUERY,
+        1,
+        "FT.SEARCH",
+        "{index}".format(index=index),
+        "@{}:[{} {}]".format(
+            timestamp_options["field"],
+            bind_param(
+                params, "from", round_timestamp(latest - timestamp_options["window"])
+            ),
+            bind_param(params, "to", round_timestamp(latest)),
+        ),
+    ]
+    append_params(cmd, params)
+    return append_search_options(cmd, search_options)
+
+
+def pad_doc(doc_id, doc, min_doc_bytes):
+    """Pads the document with a filler PADDING_FIELD, which is not part of the schema ( so it is stored but not
+    indexed ), until its estimated size reaches min_doc_bytes"""
+    missing = min_doc_bytes - estimate_doc_size(doc_id, doc) - len(PADDING_FIELD)
+    if min_doc_bytes > 0 and missing > 0:
+        doc[PADDING_FIELD] = "x" * missing
+    return doc
+
+
+def get_doc_score(doc, score_field, numeric_range):
+    """Returns the document score derived from the value of the NUMERIC score_field, normalized to [0,1] over the
+    field range, so that the ranking is correlated with the field value. Returns None ( the default score ) when
+    score_field is not set or the document is missing it"""
+    if score_field == "" or score_field not in doc:
+        return None
+    field_range = get_field_numeric_range(numeric_range, score_field)
+    span = field_range["max"] - field_range["min"]
+    if span == 0:
+        return 1.0
+    return round((doc[score_field] - field_range["min"]) / span, 6)
+
+
+def generate_ft_add_row(
+    index,
+    doc_id,
+    doc,
+    cmd_type="SETUP_WRITE",
+    query_name="S1",
+    replace=False,
+    score=None,
+):
+    if score is None:
+        score = 1.0
+    cmd = [
+        cmd_type,
+        query_name,
+        2,
+        "FT.ADD",
+        "{index}".format(index=index),
+        doc_id,
+        score,
+    ]
+    if replace:
+        cmd.append("REPLACE")
+    cmd.append("FIELDS")
+    for f, v in doc.items():
+        cmd.append(f)
+        cmd.append(v)
+    return cmd
+
+
+def generate_hset_row(
+    doc_id, doc, cmd_type="SETUP_WRITE", query_name="S1", score=None
+):
+    cmd = [cmd_type, query_name, 1, "HSET", doc_id]
+    for f, v in doc.items():
+        cmd.append(f)
+        cmd.append(v)
+    if score is not None:
+        # the document score of hashes is read from the index SCORE_FIELD, __score by default
+        cmd.append("__score")
+        cmd.append(score)
+    return cmd
+
+
+def generate_write_row(
+    use_hset,
+    index,
+    doc_id,
+    doc,
+    cmd_type="SETUP_WRITE",
+    query_name="S1",
+    replace=False,
+    score=None,
+):
+    # HSET always overwrites the hash fields, so there is no need for an explicit REPLACE
+    if use_hset:
+        return generate_hset_row(doc_id, doc, cmd_type, query_name, score)
+    return generate_ft_add_row(index, doc_id, doc, cmd_type, query_name, replace, score)
+
+
+def generate_delete_row(use_hset, index, doc_id):
+    if use_hset:
+        return ["DELETE", "D1", 1, "DEL", doc_id]
+    return ["DELETE", "D1", 2, "FT.DEL", "{index}".format(index=index), doc_id]
+
+
+def generate_dependent_read_row(use_hset, index, doc_id):
+    # read back a just written document, once the write completed
+    if use_hset:
+        return ["READ", "read-your-writes|dependent", 1, "HGETALL", doc_id]
+    return [
+        "READ",
+        "read-your-writes|dependent",
+        2,
+        "FT.GET",
+        "{index}".format(index=index),
+        doc_id,
+    ]
+
+
+def pick_doc_id_pos(live_doc_ids, id_access):
+    """Returns the position on live_doc_ids ( ordered from the oldest to the most recent inserted id ) of the
+    document to be targeted by an update or delete, following the requested access skew"""
+    n = len(live_doc_ids)
+    if id_access == RECENCY_ACCESS:
+        offset = int(random.expovariate(RECENCY_ACCESS_LAMBDA / n))
+        return n - 1 - min(offset, n - 1)
+    return random.randrange(n)
+
+
+def append_search_options(cmd, search_options):
+    """Appends the FT.SEARCH options shared by all generated search queries"""
+    if search_options["no_content"]:
+        cmd.append("NOCONTENT")
+    if search_options["verbatim_probability"] > 0.0:
+        if random.random() < search_options["verbatim_probability"]:
+            cmd.append("VERBATIM")
+    if search_options["nostopwords_probability"] > 0.0:
+        if random.random() < search_options["nostopwords_probability"]:
+            cmd.append("NOSTOPWORDS")
+    if search_options["scorer"] != "":
+        cmd.append("SCORER")
+        cmd.append(search_options["scorer"])
+    if len(search_options["return_fields"]) > 0:
+        cmd.append("RETURN")
+        cmd.append(len(search_options["return_fields"]))
+        cmd.extend(search_options["return_fields"])
+    if search_options["dialect"] > 0:
+        cmd.append("DIALECT")
+        cmd.append(search_options["dialect"])
+    return cmd
+
+
+def new_query_params(search_options):
+    """Returns the list collecting the name value pairs bound to a query, or None when the queries are not
+    parameterized"""
+    if search_options["parameterized"]:
+        return []
+    return None
+
+
+def bind_param(params, name, value):
+    """Returns the reference to be used on the query for value. On parameterized queries the value is bound
+    via PARAMS and referenced as $name, so that the same query template is reused with different values"""
+    if params is None:
+        return value
+    params.extend([name, value])
+    return "${}".format(name)
+
+
+def append_params(cmd, params):
+    """Appends the PARAMS clause with the values bound to the query, if any"""
+    if params is not None and len(params) > 0:
+        cmd.append("PARAMS")
+        cmd.append(len(params))
+        cmd.extend(params)
+    return cmd
+
+
+def generate_numeric_range_row(index, schema, numeric_range, search_options):
+    numeric_fields = [f for f, v in schema.items() if v["type"] == NUMERIC]
+    field = random.choice(numeric_fields)
+    field_range = get_field_numeric_range(numeric_range, field)
+    val_from = generate_numeric_value(field_range)
+    val_to = generate_numeric_value(field_range, val_from)
+    params = new_query_params(search_options)
+    cmd = [
+        "READ",
+        SEARCH_NUMERIC_RANGE,
+        1,
+        "FT.SEARCH",
+        "{index}".format(index=index),
+        "@{}:[{} {}]".format(
+            field,
+            bind_param(params, "from", val_from),
+            bind_param(params, "to", val_to),
+        ),
+    ]
+    append_params(cmd, params)
+    return append_search_options(cmd, search_options)
+
+
+def generate_sortby_row(index, schema, query, sort_limit, search_options):
+    field = random.choice(get_sortable_fields(schema))
+    params = None
+    if query != "*":
+        params = new_query_params(search_options)
+    cmd = [
+        "READ",
+        SORTBY_QUERY,
+        1,
+        "FT.SEARCH",
+        "{index}".format(index=index),
+        "{query}".format(query=bind_param(params, "term", query)),
+        "SORTBY",
+        field,
+        "ASC",
+        "LIMIT",
+        0,
+        sort_limit,
+    ]
+    append_params(cmd, params)
+    return append_search_options(cmd, search_options)
+
+
+def generate_range_sortby_row(
+    index, schema, numeric_range, range_selectivity, sort_limit, search_options
+):
+    """Composes a numeric range filter sorted by the same SORTABLE NUMERIC field, as an e-commerce listing would,
+    e.g. FT.SEARCH idx @numeric1:[10 100] SORTBY numeric1 ASC LIMIT 0 20. The range spans range_selectivity of the
+    field range, at a random offset"""
+    field = random.choice(get_sortable_numeric_fields(schema))
+    field_range = get_field_numeric_range(numeric_range, field)
+    width = (field_range["max"] - field_range["min"]) * range_selectivity
+    val_from = random.uniform(field_range["min"], field_range["max"] - width)
+    val_to = val_from + width
+    if field_range["precision"] > 0:
+        val_from = round(val_from, field_range["precision"])
+        val_to = round(val_to, field_range["precision"])
+    else:
+        val_from = int(math.floor(val_from))
+        val_to = int(math.ceil(val_to))
+    params = new_query_params(search_options)
+    cmd = [
+        "READ",
+        RANGE_SORTBY_QUERY,
+        1,
+        "FT.SEARCH",
+        "{index}".format(index=index),
+        "@{}:[{} {}]".format(
+            field,
+            bind_param(params, "from", val_from),
+            bind_param(params, "to", val_to),
+        ),
+        "SORTBY",
+        field,
+        "ASC",
+        "LIMIT",
+        0,
+        sort_limit,
+    ]
+    append_params(cmd, params)
+    return append_search_options(cmd, search_options)
+
+
+def generate_hybrid_row(
+    index, schema, numeric_range, vocabulary, tag_values, probabilities, search_options
+):
+    """Composes a query combining a full-text term, a numeric range and a tag filter, each included with its
+    own probability, e.g. @text1:word @numeric1:[10 20] @tag1:{tag3}"""
+    fields_of_type = {}
+    for t in [TEXT, NUMERIC, TAG]:
+        fields_of_type[t] = [f for f, v in schema.items() if v["type"] == t]
+    available = [t for t in [TEXT, NUMERIC, TAG] if len(fields_of_type[t]) > 0]
+    included = [t for t in available if random.random() < probabilities[t]]
+    # the query needs at least one clause
+    if len(included) == 0:
+        included = [random.choice(available)]
+    clauses = []
+    params = new_query_params(search_options)
+    for t in included:
+        field = random.choice(fields_of_type[t])
+        if t == TEXT:
+            term = bind_param(params, "term", random.choice(vocabulary))
+            clauses.append("@{}:{}".format(field, term))
+        elif t == NUMERIC:
+            field_range = get_field_numeric_range(numeric_range, field)
+            val_from = generate_numeric_value(field_range)
+            val_to = generate_numeric_value(field_range, val_from)
+            clauses.append(
+                "@{}:[{} {}]".format(
+                    field,
+                    bind_param(params, "from", val_from),
+                    bind_param(params, "to", val_to),
+                )
+            )
+        else:
+            tag = bind_param(params, "tag", random.choice(tag_values))
+            clauses.append("@{}:{{{}}}".format(field, tag))
+    cmd = [
+        "READ",
+        HYBRID_QUERY,
+        1,
+        "FT.SEARCH",
+        "{index}".format(index=index),
+        " ".join(clauses),
+    ]
+    append_params(cmd, params)
+    return append_search_options(cmd, search_options)
+
+
+def generate_tag_negation_row(
+    index, schema, word, tag_values, negation_probability, search_options
+):
+    """Composes a full-text term on a TEXT field filtered by a tag on a TAG field, negated with negation_probability,
+    e.g. @text1:word -@tag1:{tag3}, as the exclusion filters of a search UI would"""
+    text_field = random.choice([f for f, v in schema.items() if v["type"] == TEXT])
+    tag_field = random.choice([f for f, v in schema.items() if v["type"] == TAG])
+    params = new_query_params(search_options)
+    negation = ""
+    if random.random() < negation_probability:
+        negation = "-"
+    cmd = [
+        "READ",
+        TAG_NEGATION_QUERY,
+        1,
+        "FT.SEARCH",
+        "{index}".format(index=index),
+        "@{}:{} {}@{}:{{{}}}".format(
+            text_field,
+            bind_param(params, "term", word),
+            negation,
+            tag_field,
+            bind_param(params, "tag", random.choice(tag_values)),
+        ),
+    ]
+    append_params(cmd, params)
+    return append_search_options(cmd, search_options)
+
+
+def generate_geoshape_row(index, schema, geoshape_options, search_options):
+    """Composes a parameterized spatial query, e.g. @geom1:[WITHIN $shape] PARAMS 2 shape POLYGON((...)).
+    WITHIN queries match the documents inside a polygon, while CONTAINS queries match the documents containing
+    a point"""
+    field = random.choice([f for f, v in schema.items() if v["type"] == GEOSHAPE])
+    predicate = geoshape_options["predicate"]
+    if predicate == "WITHIN":
+        shape = generate_wkt_polygon(
+            geoshape_options["extent"],
+            geoshape_options["vertices"],
+            geoshape_options["query_radius"],
+        )
+    else:
+        shape = generate_wkt_point(geoshape_options["extent"])
+    cmd = [
+        "READ",
+        GEOSHAPE_QUERY,
+        1,
+        "FT.SEARCH",
+        "{index}".format(index=index),
+        "@{}:[{} $shape]".format(field, predicate),
+    ]
+    append_params(cmd, ["shape", shape])
+    # GEOSHAPE queries require at least DIALECT 3
+    options = dict(search_options)
+    options["dialect"] = max(3, search_options["dialect"])
+    return append_search_options(cmd, options)
+
+
+def generate_ismissing_row(index, schema, search_options):
+    """Composes a query matching the documents missing a random field of the schema, e.g. ismissing(@numeric1)"""
+    field = random.choice(get_indexmissing_fields(schema))
+    cmd = [
+        "READ",
+        ISMISSING_QUERY,
+        1,
+        "FT.SEARCH",
+        "{index}".format(index=index),
+        "ismissing(@{})".format(field),
+    ]
+    # ismissing() requires at least DIALECT 2
+    options = dict(search_options)
+    options["dialect"] = max(2, search_options["dialect"])
+    return append_search_options(cmd, options)
+
+
+def generate_affix_row(index, query_name, word, affix_length, search_options):
+    """Composes a suffix (*fix) or contains (*ntai*) query from the last or middle affix_length characters of
+    word. Both are served by the suffix trie of the TEXT fields created WITHSUFFIXTRIE, or by scanning all terms
+    otherwise"""
+    if query_name == SUFFIX_QUERY:
+        query = "*" + word[-affix_length:]
+    else:
+        start = max(0, (len(word) - affix_length) // 2)
+        query = "*" + word[start : start + affix_length] + "*"
+    cmd = [
+        "READ",
+        query_name,
+        1,
+        "FT.SEARCH",
+        "{index}".format(index=index),
+        query,
+    ]
+    return append_search_options(cmd, search_options)
+
+
+def escape_query_term(word):
+    """Escapes the characters of word that the query parser would otherwise interpret as separators or operators"""
+    return re.sub(r"(\W)", r"\\\1", word)
+
+
+def generate_boolean_expression(vocabulary, depth, width, negation_probability, params):
+    """Composes a random boolean expression of up to depth nested groups, each with 2 to width sub expressions
+    joined by an intersection ( space ) or a union ( | ). The first sub expression of each group is the one reaching
+    the full depth, and is never negated, while the others are nested groups or terms negated ( -term ) with
+    negation_probability"""
+    operator = random.choice([" ", "|"])
+    children = []
+    for i in range(0, random.randint(2, width)):
+        if depth > 1 and (i == 0 or random.random() < 0.5):
+            child = "({})".format(
+                generate_boolean_expression(
+                    vocabulary, depth - 1, width, negation_probability, params
+                )
+            )
+        else:
+            word = random.choice(vocabulary)
+            if params is None:
+                child = escape_query_term(word)
+            else:
+                child = bind_param(params, "term{}".format(len(params) // 2 + 1), word)
+        if i > 0 and random.random() < negation_probability:
+            child = "-" + child
+        children.append(child)
+    return operator.join(children)
+
+
+def generate_boolean_row(
+    index, vocabulary, depth, width, negation_probability, search_options
+):
+    params = new_query_params(search_options)
+    cmd = [
+        "READ",
+        BOOLEAN_QUERY,
+        1,
+        "FT.SEARCH",
+        "{index}".format(index=index),
+        generate_boolean_expression(
+            vocabulary, depth, width, negation_probability, params
+        ),
+    ]
+    append_params(cmd, params)
+    return append_search_options(cmd, search_options)
+
+
+def generate_apply_expression(numeric_fields, loaded):
+    """Composes a math expression over 2 or 3 NUMERIC fields, each optionally wrapped on a math function, e.g.
+    sqrt(@numeric1) * @numeric4 - log(@numeric2). The referenced fields are added to loaded"""
+    expression = ""
+    for i, field in enumerate(random.choices(numeric_fields, k=random.randint(2, 3))):
+        loaded.add(field)
+        term = "@{}".format(field)
+        if random.random() < 0.5:
+            term = "{}({})".format(
+                random.choice(["abs", "sqrt", "log", "floor", "ceil"]), term
+            )
+        if i > 0:
+            expression = expression + " {} ".format(random.choice(["+", "-", "*", "/"]))
+        expression = expression + term
+    return expression
+
+
+def generate_apply_filter_row(
+    index, schema, numeric_range, apply_expressions, filter_predicates, search_options
+):
+    """Composes an aggregation like FT.AGGREGATE idx * LOAD 2 @numeric1 @numeric4 APPLY "sqrt(@numeric1) * @numeric4"
+    AS apply1 FILTER "@numeric1 < 5000", evaluating apply_expressions APPLY math expressions over the NUMERIC fields
+    of every document, filtered by filter_predicates comparisons of a NUMERIC field against a value of its range"""
+    numeric_fields = [f for f, v in schema.items() if v["type"] == NUMERIC]
+    loaded = set()
+    steps = []
+    for n in range(1, apply_expressions + 1):
+        steps.extend(
+            [
+                "APPLY",
+                generate_apply_expression(numeric_fields, loaded),
+                "AS",
+                "apply{}".format(n),
+            ]
+        )
+    predicates = []
+    for _ in range(filter_predicates):
+        field = random.choice(numeric_fields)
+        loaded.add(field)
+        predicates.append(
+            "@{} {} {}".format(
+                field,
+                random.choice(["<", ">"]),
+                generate_numeric_value(get_field_numeric_range(numeric_range, field)),
+            )
+        )
+    if len(predicates) > 0:
+        steps.extend(["FILTER", " && ".join(predicates)])
+    # the fields used by the expressions have to be loaded from the documents, unless they are SORTABLE
+    loaded = [f for f in numeric_fields if f in loaded]
+    cmd = [
+        "READ",
+        APPLY_FILTER_QUERY,
+        1,
+        "FT.AGGREGATE",
+        "{index}".format(index=index),
+        "*",
+        "LOAD",
+        len(loaded),
+    ]
+    cmd.extend(["@{}".format(f) for f in loaded])
+    cmd.extend(steps)
+    if search_options["dialect"] > 0:
+        cmd.append("DIALECT")
+        cmd.append(search_options["dialect"])
+    return cmd
+
+
+def generate_infields_row(index, word, infields, infields_count, search_options):
+    """Composes a term query restricted to infields_count random fields of infields, e.g.
+    FT.SEARCH idx term INFIELDS 1 text2, matching the term on those fields only without the @field: scoping syntax"""
+    params = new_query_params(search_options)
+    fields = random.sample(infields, infields_count)
+    cmd = [
+        "READ",
+        INFIELDS_QUERY,
+        1,
+        "FT.SEARCH",
+        "{index}".format(index=index),
+        "{query}".format(query=bind_param(params, "term", word)),
+        "INFIELDS",
+        len(fields),
+    ]
+    cmd.extend(fields)
+    append_params(cmd, params)
+    return append_search_options(cmd, search_options)
+
+
+def generate_stopword_row(index, search_options):
+    """Composes a query of 1 to 3 stopwords, e.g. FT.SEARCH idx "the of", which is left empty once the stopwords are
+    filtered out. NOSTOPWORDS is never set, given it would turn it into a regular query"""
+    words = random.choices(DEFAULT_STOPWORDS, k=random.randint(1, 3))
+    cmd = [
+        "READ",
+        STOPWORD_QUERY,
+        1,
+        "FT.SEARCH",
+        "{index}".format(index=index),
+        " ".join(words),
+    ]
+    options = dict(search_options)
+    options["nostopwords_probability"] = 0.0
+    return append_search_options(cmd, options)
+
+
+def generate_sugadd_row(suggestion_key, word, score):
+    return ["SETUP_WRITE", SUGADD, 1, "FT.SUGADD", suggestion_key, word, score]
+
+
+def generate_alter_row(index, field, field_type):
+    return [
+        "SETUP_WRITE",
+        ALTER,
+        1,
+        "FT.ALTER",
+        index,
+        "SCHEMA",
+        "ADD",
+        field,
+        field_type,
+    ]
+
+
+def generate_sugget_row(suggestion_key, word, prefix_length):
+    """Generates an autocomplete query for the first prefix_length characters of word"""
+    return [
+        "READ",
+        SUGGET_QUERY,
+        1,
+        "FT.SUGGET",
+        suggestion_key,
+        word[:prefix_length],
+        "FUZZY",
+    ]
+
+
+def generate_ft_search_row(index, query_name, query, search_options):
+    params = new_query_params(search_options)
+    cmd = [
+        "READ",
+        query_name,
+        1,
+        "FT.SEARCH",
+        "{index}".format(index=index),
+        "{query}".format(query=bind_param(params, "term", query)),
+    ]
+    append_params(cmd, params)
+    return append_search_options(cmd, search_options)
+
+
+if __name__ == "__main__":
+    parser = argparse.ArgumentParser(
+        description="RediSearch FTSB synthetic data generator.",
+        formatter_class=argparse.ArgumentDefaultsHelpFormatter,
+    )
+    parser.add_argument(
+        "--project", type=str, default="redisearch", help="the project being tested"
+    )
+    parser.add_argument(
+        "--index-name",
+        type=str,
+        default="idx:synthetic",
+        help="the index name used for search commands",
+    )
+    parser.add_argument(
+        "--schema-from-index",
+        type=str,
+        default="",
+        help="host:port of a server holding an existing --index-name. When set, the schema is discovered via FT.INFO and a read-only benchmark is generated against its fields, without any setup documents nor FT.CREATE and FT.DROP commands",
+    )
+    parser.add_argument(
+        "--schema-from-index-password",
+        type=str,
+        default="",
+        help="the password used to authenticate on the --schema-from-index server",
+    )
+    parser.add_argument(
+        "--seed",
+        type=int,
+        default=12345,
+        help="the random seed used to generate random deterministic outputs",
+    )
+    parser.add_argument(
+        "--query-choices",
+        type=str,
+        default=SEARCH_NUMERIC_RANGE,
+        help="comma separated list of queries to produce. one of: {}".format(
+            choices_str
+        ),
+    )
+    parser.add_argument(
+        "--doc-limit",
+        type=int,
+        default=1000000,
+        help="the total documents to generate to be added in the setup stage",
+    )
+    parser.add_argument(
+        "--target-dataset-size",
+        type=str,
+        default="0",
+        help="keep generating documents until the cumulative estimated dataset size reaches this value (e.g. 512M, 10GB). When set to a value larger than 0 it takes precedence over --doc-limit",
+    )
+    parser.add_argument(
+        "--use-hset",
+        default=False,
+        action="store_true",
+        help="Use HSET on hash keys auto-indexed via FT.CREATE ... ON HASH PREFIX instead of FT.ADD",
+    )
+    parser.add_argument(
+        "--no-index",
+        default=False,
+        action="store_true",
+        help="baseline to quantify the indexing cost: load the same documents as plain hashes (implies --use-hset), without creating or dropping any index on the setup and teardown commands",
+    )
+    parser.add_argument(
+        "--doc-prefix",
+        type=str,
+        default="doc:",
+        help="the key prefix of the generated documents",
+    )
+    parser.add_argument(
+        "--target-slots",
+        type=int,
+        default=0,
+        help="when loading into a cluster, spread the document keys evenly across this number of cluster slots (evenly spaced across the slots space) via hash tags, e.g. doc:{12}345, avoiding hot shards during ingestion. Set it to the number of shards (or a multiple of it). 0 disables the hash tags",
+    )
+    parser.add_argument(
+        "--numeric-fields",
+        type=int,
+        default=10,
+        help="the number of NUMERIC fields per document",
+    )
+    parser.add_argument(
+        "--max-cardinality",
+        type=int,
+        default=1000000,
+        help="the maximum number of distinct values per NUMERIC field",
+    )
+    parser.add_argument(
+        "--field-cardinalities",
+        type=str,
+        default="",
+        help="comma separated list with the number of distinct values of each NUMERIC field, in order (e.g. 2,100,1000000 for numeric1, numeric2 and numeric3), mixing low and high cardinality fields. Fields not listed use --max-cardinality. Can't be used with --numeric-max",
+    )
+    parser.add_argument(
+        "--numeric-min",
+        type=float,
+        default=0,
+        help="the minimum value of the NUMERIC fields. Can be negative",
+    )
+    parser.add_argument(
+        "--numeric-max",
+        type=float,
+        default=None,
+        help="the maximum value of the NUMERIC fields. If not set, it is --numeric-min + --max-cardinality - 1",
+    )
+    parser.add_argument(
+        "--numeric-precision",
+        type=int,
+        default=0,
+        help="the number of decimal digits of the NUMERIC field values. 0 generates integer values",
+    )
+    parser.add_argument(
+        "--sortable-fields",
+        type=str,
+        default="numeric1",
+        help="comma separated list of fields declared as SORTABLE on the index. The sortby queries only sort by these fields",
+    )
+    parser.add_argument(
+        "--min-doc-bytes",
+        type=int,
+        default=0,
+        help="pad the documents smaller than this estimated size (the sum of the document id, field names and values) with a filler {} field, which is stored but not indexed, so that storage and memory footprints are comparable across schema variations (0 = no padding)".format(
+            PADDING_FIELD
+        ),
+    )
+    parser.add_argument(
+        "--timestamp-interval",
+        type=float,
+        default=0.0,
+        help="when larger than 0, each document gets a monotonically increasing NUMERIC --timestamp-field, spaced by this interval from the previous document, modeling events ingestion (0 = no timestamp field)",
+    )
+    parser.add_argument(
+        "--timestamp-start",
+        type=float,
+        default=1600000000,
+        help="the timestamp of the first document. Requires --timestamp-interval",
+    )
+    parser.add_argument(
+        "--timestamp-jitter",
+        type=float,
+        default=0.0,
+        help="random jitter, within [0,jitter[ and smaller than --timestamp-interval, added to each document timestamp. Requires --timestamp-interval",
+    )
+    parser.add_argument(
+        "--timestamp-field",
+        type=str,
+        default="timestamp",
+        help="the name of the timestamp field. Requires --timestamp-interval",
+    )
+    parser.add_argument(
+        "--timestamp-window",
+        type=float,
+        default=3600,
+        help="the width of the time window queried by the {} queries, ending at the latest document timestamp".format(
+            RECENT_WINDOW_QUERY
+        ),
+    )
+    parser.add_argument(
+        "--score-from-field",
+        type=str,
+        default="",
+        help="NUMERIC field (e.g. numeric1) to derive the document score from, normalized to [0,1] over the field range, instead of the constant 1.0 score. Correlates the ranking with the field value (e.g. popularity). The score is set on FT.ADD, or on the __score hash field with --use-hset",
+    )
+    parser.add_argument(
+        "--sort-limit",
+        type=int,
+        default=10,
+        help="the number of results (K) requested by the sortby and {} queries, via LIMIT 0 K".format(
+            RANGE_SORTBY_QUERY
+        ),
+    )
+    parser.add_argument(
+        "--range-selectivity",
+        type=float,
+        default=0.1,
+        help="the fraction, within ]0,1], of the NUMERIC field range spanned by the range filter of the {} queries".format(
+            RANGE_SORTBY_QUERY
+        ),
+    )
+    parser.add_argument(
+        "--text-fields",
+        type=int,
+        default=0,
+        help="the number of TEXT fields per document",
+    )
+    parser.add_argument(
+        "--with-suffix-trie",
+        default=False,
+        action="store_true",
+        help="create the TEXT fields WITHSUFFIXTRIE, trading memory for faster {} and {} queries".format(
+            SUFFIX_QUERY, CONTAINS_QUERY
+        ),
+    )
+    parser.add_argument(
+        "--affix-length",
+        type=int,
+        default=3,
+        help="the number of characters of the vocabulary words used on the {} and {} queries".format(
+            SUFFIX_QUERY, CONTAINS_QUERY
+        ),
+    )
+    parser.add_argument(
+        "--infields-fields",
+        type=str,
+        default="",
+        help="comma separated list of the TEXT fields the {} queries are restricted to, via INFIELDS n field1 ... fieldn. If not set, all the TEXT fields of the schema are candidates".format(
+            INFIELDS_QUERY
+        ),
+    )
+    parser.add_argument(
+        "--infields-count",
+        type=int,
+        default=1,
+        help="the number of fields, randomly picked from --infields-fields, each {} query is restricted to".format(
+            INFIELDS_QUERY
+        ),
+    )
+    parser.add_argument(
+        "--bool-depth",
+        type=int,
+        default=2,
+        help="the maximum nesting depth of the {} boolean expressions".format(
+            BOOLEAN_QUERY
+        ),
+    )
+    parser.add_argument(
+        "--bool-width",
+        type=int,
+        default=3,
+        help="the maximum number of sub expressions (at least 2) of each group of the {} boolean expressions".format(
+            BOOLEAN_QUERY
+        ),
+    )
+    parser.add_argument(
+        "--bool-negation-probability",
+        type=float,
+        default=0.2,
+        help="the probability of each sub expression (besides the first one of each group) of the {} boolean expressions being negated (-term)".format(
+            BOOLEAN_QUERY
+        ),
+    )
+    parser.add_argument(
+        "--tag-negation-probability",
+        type=float,
+        default=1.0,
+        help="the probability of the tag filter of the {} queries being negated (-@tag:{{value}}). Otherwise the filter is kept as is, as a baseline of the negated ones".format(
+            TAG_NEGATION_QUERY
+        ),
+    )
+    parser.add_argument(
+        "--apply-expressions",
+        type=int,
+        default=2,
+        help="the number of APPLY math expressions (each over 2 or 3 NUMERIC fields) of the {} aggregations".format(
+            APPLY_FILTER_QUERY
+        ),
+    )
+    parser.add_argument(
+        "--filter-predicates",
+        type=int,
+        default=1,
+        help="the number of NUMERIC field comparisons, joined by &&, of the FILTER clause of the {} aggregations. 0 disables the FILTER clause".format(
+            APPLY_FILTER_QUERY
+        ),
+    )
+    parser.add_argument(
+        "--suggestion-key",
+        type=str,
+        default="sug:synthetic",
+        help="the key of the suggestion dictionary populated (via FT.SUGADD) with the vocabulary words on the setup stage, and queried by the {} queries".format(
+            SUGGET_QUERY
+        ),
+    )
+    parser.add_argument(
+        "--suggestion-prefix-length",
+        type=int,
+        default=3,
+        help="the number of characters of the vocabulary words used as prefix on the {} queries".format(
+            SUGGET_QUERY
+        ),
+    )
+    parser.add_argument(
+        "--tag-fields",
+        type=int,
+        default=0,
+        help="the number of TAG fields per document",
+    )
+    parser.add_argument(
+        "--tag-cardinality",
+        type=int,
+        default=100,
+        help="the number of distinct values per TAG field",
+    )
+    parser.add_argument(
+        "--geoshape-fields",
+        type=int,
+        default=0,
+        help="the number of GEOSHAPE fields per document, holding WKT points or polygons",
+    )
+    parser.add_argument(
+        "--geoshape-coord-system",
+        type=str,
+        default=FLAT,
+        choices=[FLAT, SPHERICAL],
+        help="the coordinate system of the GEOSHAPE fields. FLAT geometries are placed within [0,1000] on both axes, and SPHERICAL ones within the longitude [-180,180] and latitude [-85,85] ranges",
+    )
+    parser.add_argument(
+        "--geoshape-point-probability",
+        type=float,
+        default=0.5,
+        help="probability of a document GEOSHAPE field holding a point instead of a polygon",
+    )
+    parser.add_argument(
+        "--polygon-vertices",
+        type=int,
+        default=8,
+        help="the number of vertices of the generated polygons (both on documents and queries), controlling the shapes complexity",
+    )
+    parser.add_argument(
+        "--polygon-radius",
+        type=float,
+        default=10.0,
+        help="the maximum distance of the documents polygon vertices to their center",
+    )
+    parser.add_argument(
+        "--geoshape-query-radius",
+        type=float,
+        default=50.0,
+        help="the maximum distance of the query polygon vertices to their center, controlling the WITHIN queries selectivity",
+    )
+    parser.add_argument(
+        "--geoshape-predicate",
+        type=str,
+        default="WITHIN",
+        choices=["WITHIN", "CONTAINS"],
+        help="spatial predicate of the {} queries. WITHIN matches the documents inside a query polygon, and CONTAINS the documents containing a query point".format(
+            GEOSHAPE_QUERY
+        ),
+    )
+    parser.add_argument(
+        "--hybrid-text-probability",
+        type=float,
+        default=1.0,
+        help="probability of a hybrid query including a full-text term on a TEXT field",
+    )
+    parser.add_argument(
+        "--hybrid-numeric-probability",
+        type=float,
+        default=0.5,
+        help="probability of a hybrid query including a range filter on a NUMERIC field",
+    )
+    parser.add_argument(
+        "--hybrid-tag-probability",
+        type=float,
+        default=0.5,
+        help="probability of a hybrid query including a filter on a TAG field",
+    )
+    parser.add_argument(
+        "--text-field-weights",
+        type=str,
+        default="",
+        help="comma separated list of field=weight pairs (e.g. text1=2.0,text2=0.5) setting the WEIGHT of TEXT fields on the index, used when scoring the search results. Fields not listed keep the default weight of 1.0",
+    )
+    parser.add_argument(
+        "--dictionary-file",
+        type=str,
+        default="",
+        help="file with one word per line used to populate the TEXT fields. If not set, random words are generated",
+    )
+    parser.add_argument(
+        "--field-correlations",
+        type=str,
+        default="",
+        help='JSON file with a lookup table of correlated fields, so that the value of each of them is picked among the candidates for the value of the field it depends on (e.g. {"tag2": {"depends_on": "tag1", "values": {"tag1": ["tag1", "tag2"]}}}), producing realistic filter combination selectivities. If not set, the fields are independent',
+    )
+    parser.add_argument(
+        "--vocab-size",
+        type=int,
+        default=10000,
+        help="the number of distinct words used to populate the TEXT fields",
+    )
+    parser.add_argument(
+        "--words-per-doc",
+        type=int,
+        default=100,
+        help="the number of words per document, spread evenly across the TEXT fields",
+    )
+    parser.add_argument(
+        "--doc-size-distribution",
+        type=str,
+        default=CONSTANT_DISTRIBUTION,
+        choices=distributions_str.split(","),
+        help="the distribution of the number of words per document, with mean --words-per-doc",
+    )
+    parser.add_argument(
+        "--doc-size-sigma",
+        type=float,
+        default=1.0,
+        help="the standard deviation of the underlying normal distribution, when using the lognormal --doc-size-distribution. Larger values produce a larger size skew",
+    )
+    parser.add_argument(
+        "--shuffle-fields",
+        default=False,
+        action="store_true",
+        help="emit the fields of each document (FT.ADD/HSET) on a random order, instead of the schema order, to ensure the benchmark doesn't accidentally exploit a fixed fields order",
+    )
+    parser.add_argument(
+        "--missing-rate",
+        type=float,
+        default=0.0,
+        help="the rate of fields omitted from each document (FT.ADD/HSET), producing sparse documents. At least one field is kept per document. When above 0 the schema fields are created INDEXMISSING, so that the {} queries can match the documents missing them".format(
+            ISMISSING_QUERY
+        ),
+    )
+    parser.add_argument(
+        "--total-benchmark-commands",
+        type=int,
+        default=1000000,
+        help="the total commands to generate to be issued in the benchmark stage",
+    )
+    parser.add_argument(
+        "--write-ratio",
+        type=float,
+        default=0.0,
+        help="the ratio of benchmark commands that insert new documents",
+    )
+    parser.add_argument(
+        "--update-ratio",
+        type=float,
+        default=0.0,
+        help="the ratio of benchmark commands that re-index (FT.ADD ... REPLACE) an already inserted document with new field values",
+    )
+    parser.add_argument(
+        "--delete-ratio",
+        type=float,
+        default=0.0,
+        help="the ratio of benchmark commands that delete (FT.DEL or DEL when using --use-hset) an already inserted document. The read ratio will be given by (1 - write-ratio - update-ratio - delete-ratio)",
+    )
+    parser.add_argument(
+        "--duplicate-rate",
+        type=float,
+        default=0.0,
+        help="the rate of insert commands (on both the setup and benchmark stages) that resend an already inserted document id with new field values, exercising the overwrite (delete then reindex) path. Duplicates use the same insert command, with REPLACE when not using --use-hset",
+    )
+    parser.add_argument(
+        "--read-your-writes-probability",
+        type=float,
+        default=0.0,
+        help="the probability of each benchmark write and update being followed by a read of the same document (HGETALL, or FT.GET when not using --use-hset), marked as dependent on it (<query group>|dependent) so that ftsb waits -think-time before issuing it. The dependent reads take the place of other benchmark commands",
+    )
+    parser.add_argument(
+        "--alter-every",
+        type=int,
+        default=0,
+        help="issues an FT.ALTER adding a new field to the live index every N setup documents (labeled as SETUP_WRITE with the alter query group), benchmarking the cost and impact of evolving the schema while loading. The following documents also populate the added fields. 0 disables it",
+    )
+    parser.add_argument(
+        "--alter-field-type",
+        type=str,
+        default=NUMERIC,
+        choices=[NUMERIC, TEXT, TAG],
+        help="the type of the fields added with --alter-every",
+    )
+    parser.add_argument(
+        "--id-space",
+        type=int,
+        default=0,
+        help="the number of document ids ({doc-prefix}0 ... {doc-prefix}N-1) already inserted before the benchmark stage, and targeted by updates and deletes. Set it to the --doc-limit used for generating the setup stage when it is generated separately. 0 uses the documents generated in this setup stage",
+    )
+    parser.add_argument(
+        "--id-access-distribution",
+        type=str,
+        default=UNIFORM_ACCESS,
+        choices=id_access_str.split(","),
+        help="the access skew of updates and deletes across the id space. recency biases the accesses towards the most recently inserted documents",
+    )
+    parser.add_argument(
+        "--search-no-content",
+        default=False,
+        action="store_true",
+        help="When doing search queries, only return the document ids and not the content",
+    )
+    parser.add_argument(
+        "--return-fields",
+        type=str,
+        default="",
+        help="comma separated list of fields to retrieve on search queries, via RETURN n field1 ... fieldn. If not set, the full documents are returned",
+    )
+    parser.add_argument(
+        "--verbatim-probability",
+        type=float,
+        default=0.0,
+        help="probability of a search query being issued with VERBATIM, disabling the stemming/expansion of the query terms",
+    )
+    parser.add_argument(
+        "--nostopwords-probability",
+        type=float,
+        default=0.0,
+        help="probability of a search query being issued with NOSTOPWORDS, disabling the filtering of stopwords from the query terms",
+    )
+    parser.add_argument(
+        "--stopword-query-rate",
+        type=float,
+        default=0.0,
+        help="fraction of the search queries replaced by a {} query, composed only of stopwords, probing how queries left empty after the stopwords removal are handled. They are labeled apart, so that their latency is reported separately".format(
+            STOPWORD_QUERY
+        ),
+    )
+    parser.add_argument(
+        "--scorer",
+        type=str,
+        default="",
+        choices=[""] + scorers,
+        help="scoring function to use on search queries, via SCORER name. If not set, the server default scorer is used",
+    )
+    parser.add_argument(
+        "--dialect",
+        type=int,
+        default=0,
+        help="query dialect to use on search queries, via DIALECT n (choices: 1, 2, 3, 4). If not set, the server default dialect is used",
+    )
+    parser.add_argument(
+        "--parameterized-queries",
+        default=False,
+        action="store_true",
+        help="issue the search queries as templates, with the terms, ranges and tags bound via PARAMS and referenced as $name, so that the same query template is reused with different values. Requires DIALECT 2 or greater, which is used by default",
+    )
+    parser.add_argument(
+        "--test-name",
+        type=str,
+        default="synthetic",
+        help="the name of the test",
+    )
+    parser.add_argument(
+        "--test-description",
+        type=str,
+        default="benchmark making usage of synthetic randomly generated documents.",
+        help="the full description of the test",
+    )
+    parser.add_argument(
+        "--resp-setup",
+        default=False,
+        action="store_true",
+        help="also writes the setup commands (index creation and documents ingestion) as a RESP stream file, that can be bulk loaded via redis-cli --pipe",
+    )
+    parser.add_argument(
+        "--output-buffer-size",
+        type=str,
+        default="0",
+        help="write buffer size of the generated files (e.g. 64K, 8M). Larger buffers speed up the generation of huge files. If 0, the default buffer size is used",
+    )
+    parser.add_argument(
+        "--fsync-every",
+        type=int,
+        default=0,
+        help="flush and fsync the generated files to disk every N commands, so that the commands generated so far are recoverable if a multi-hour generation is interrupted (0 = disabled)",
+    )
+    parser.add_argument(
+        "--upload-artifacts-s3",
+        default=False,
+        action="store_true",
+        help="uploads the generated dataset files and configuration file to public benchmarks.redislabs bucket. Proper credentials are required",
+    )
+
+    args = parser.parse_args()
+    use_case_specific_arguments = del_non_use_case_specific_keys(dict(args.__dict__))
+    # the password is not to be persisted on the benchmark config
+    del use_case_specific_arguments["schema_from_index_password"]
+    query_choices = args.query_choices.split(",")
+    total_benchmark_commands = args.total_benchmark_commands
+    project = args.project
+    doc_limit = args.doc_limit
+    target_dataset_size = parse_size(args.target_dataset_size)
+    max_cardinality = args.max_cardinality
+    discovered_schema = None
+    if args.schema_from_index != "":
+        for ratio in ["write_ratio", "update_ratio", "delete_ratio"]:
+            if getattr(args, ratio) > 0.0:
+                raise ValueError(
+                    "--schema-from-index generates read-only benchmarks, so --{} must be 0".format(
+                        ratio.replace("_", "-")
+                    )
+                )
+        if args.no_index or args.alter_every > 0:
+            raise ValueError(
+                "--schema-from-index can't be used with --no-index nor --alter-every"
+            )
+        discovered_schema = get_index_schema(
+            args.schema_from_index, args.schema_from_index_password, args.index_name
+        )
+        if len(discovered_schema) == 0:
+            raise ValueError(
+                "index {} has no field the queries can target".format(args.index_name)
+            )
+        print(
+            "Discovered the fields {} of index {}".format(
+                ",".join(discovered_schema), args.index_name
+            )
+        )
+        # the queries target the discovered fields, and there are no documents to generate
+        for t, fields_arg in [
+            (NUMERIC, "numeric_fields"),
+            (TEXT, "text_fields"),
+            (TAG, "tag_fields"),
+            (GEOSHAPE, "geoshape_fields"),
+        ]:
+            fields = [f for f, v in discovered_schema.items() if v["type"] == t]
+            setattr(args, fields_arg, len(fields))
+            if t == GEOSHAPE and len(fields) > 0:
+                args.geoshape_coord_system = discovered_schema[fields[0]][
+                    "field_options"
+                ][0]
+        args.field_cardinalities = ""
+        doc_limit = 0
+        target_dataset_size = 0
+    numeric_range = {
+        "min": args.numeric_min,
+        "max": args.numeric_max,
+        "precision": args.numeric_precision,
+        "field_max": {},
+    }
+    field_cardinalities = parse_field_cardinalities(
+        args.field_cardinalities, args.numeric_fields
+    )
+    if len(field_cardinalities) > 0 and numeric_range["max"] is not None:
+        raise ValueError("--field-cardinalities can't be used with --numeric-max")
+    if numeric_range["max"] is None:
+        numeric_range["max"] = numeric_range["min"] + max_cardinality - 1
+    if numeric_range["precision"] == 0:
+        numeric_range["min"] = int(math.floor(numeric_range["min"]))
+        numeric_range["max"] = int(math.floor(numeric_range["max"]))
+    if numeric_range["min"] > numeric_range["max"]:
+        raise ValueError("--numeric-min can't be greater than --numeric-max")
+    for n, cardinality in enumerate(field_cardinalities, start=1):
+        numeric_range["field_max"]["numeric{}".format(n)] = (
+            numeric_range["min"] + cardinality - 1
+        )
+    search_no_content = args.search_no_content
+    write_ratio = args.write_ratio
+    update_ratio = args.update_ratio
+    delete_ratio = args.delete_ratio
+    read_ratio = 1.0 - write_ratio - update_ratio - delete_ratio
+    if read_ratio < 0.0:
+        raise ValueError(
+            "the sum of --write-ratio, --update-ratio and --delete-ratio can't exceed 1"
+        )
+    id_space = args.id_space
+    if id_space < 0:
+        raise ValueError("--id-space can't be negative")
+    id_access = args.id_access_distribution
+    duplicate_rate = args.duplicate_rate
+    if duplicate_rate < 0.0 or duplicate_rate > 1.0:
+        raise ValueError("--duplicate-rate must be within [0,1]")
+    read_your_writes_probability = args.read_your_writes_probability
+    if read_your_writes_probability < 0.0 or read_your_writes_probability > 1.0:
+        raise ValueError("--read-your-writes-probability must be within [0,1]")
+    missing_rate = args.missing_rate
+    if missing_rate < 0.0 or missing_rate >= 1.0:
+        raise ValueError("--missing-rate must be within [0,1[")
+    if (
+        ISMISSING_QUERY in query_choices
+        and missing_rate == 0.0
+        and discovered_schema is None
+    ):
+        raise ValueError("{} requires --missing-rate".format(ISMISSING_QUERY))
+    alter_every = args.alter_every
+    if alter_every < 0:
+        raise ValueError("--alter-every can't be negative")
+    search_options = {
+        "no_content": search_no_content,
+        "return_fields": [f for f in args.return_fields.split(",") if f != ""],
+        "dialect": args.dialect,
+        "scorer": args.scorer,
+        "verbatim_probability": args.verbatim_probability,
+        "nostopwords_probability": args.nostopwords_probability,
+        "parameterized": args.parameterized_queries,
+    }
+    for probability in ["verbatim_probability", "nostopwords_probability"]:
+        if search_options[probability] < 0.0 or search_options[probability] > 1.0:
+            raise ValueError(
+                "--{} must be within [0,1]".format(probability.replace("_", "-"))
+            )
+    stopword_query_rate = args.stopword_query_rate
+    if stopword_query_rate < 0.0 or stopword_query_rate > 1.0:
+        raise ValueError("--stopword-query-rate must be within [0,1]")
+    hybrid_probabilities = {
+        TEXT: args.hybrid_text_probability,
+        NUMERIC: args.hybrid_numeric_probability,
+        TAG: args.hybrid_tag_probability,
+    }
+    for t, probability in hybrid_probabilities.items():
+        if probability < 0.0 or probability > 1.0:
+            raise ValueError(
+                "--hybrid-{}-probability must be within [0,1]".format(t.lower())
+            )
+    if (
+        HYBRID_QUERY in query_choices
+        and args.numeric_fields + args.text_fields + args.tag_fields == 0
+    ):
+        raise ValueError("{} requires at least one field".format(HYBRID_QUERY))
+    if APPLY_FILTER_QUERY in query_choices and args.numeric_fields == 0:
+        raise ValueError("{} requires --numeric-fields".format(APPLY_FILTER_QUERY))
+    if args.apply_expressions < 1:
+        raise ValueError("--apply-expressions must be at least 1")
+    if args.filter_predicates < 0:
+        raise ValueError("--filter-predicates can't be negative")
+    if args.min_doc_bytes < 0:
+        raise ValueError("--min-doc-bytes can't be negative")
+    timestamp_options = None
+    if args.timestamp_interval < 0.0:
+        raise ValueError("--timestamp-interval can't be negative")
+    if args.timestamp_interval > 0.0:
+        if (
+            args.timestamp_jitter < 0.0
+            or args.timestamp_jitter >= args.timestamp_interval
+        ):
+            raise ValueError(
+                "--timestamp-jitter must be within [0,--timestamp-interval["
+            )
+        if args.timestamp_window <= 0.0:
+            raise ValueError("--timestamp-window must be positive")
+        timestamp_options = {
+            "field": args.timestamp_field,
+            "start": args.timestamp_start,
+            "interval": args.timestamp_interval,
+            "jitter": args.timestamp_jitter,
+            "window": args.timestamp_window,
+        }
+    if RECENT_WINDOW_QUERY in query_choices and timestamp_options is None:
+        raise ValueError("{} requires --timestamp-interval".format(RECENT_WINDOW_QUERY))
+    geoshape_options = None
+    if args.geoshape_fields > 0:
+        if args.polygon_vertices < 3:
+            raise ValueError("--polygon-vertices must be at least 3")
+        extent = geoshape_extents[args.geoshape_coord_system]
+        max_radius = min(
+            extent["max_x"] - extent["min_x"], extent["max_y"] - extent["min_y"]
+        )
+        for radius in [args.polygon_radius, args.geoshape_query_radius]:
+            if radius <= 0.0 or 2 * radius >= max_radius:
+                raise ValueError(
+                    "the polygon radius must be within ]0,{}[".format(max_radius / 2)
+                )
+        geoshape_options = {
+            "extent": extent,
+            "point_probability": args.geoshape_point_probability,
+            "vertices": args.polygon_vertices,
+            "radius": args.polygon_radius,
+            "query_radius": args.geoshape_query_radius,
+            "predicate": args.geoshape_predicate,
+        }
+    for choice in [SUFFIX_QUERY, CONTAINS_QUERY, BOOLEAN_QUERY, INFIELDS_QUERY]:
+        if choice in query_choices and args.text_fields == 0:
+            raise ValueError("{} requires --text-fields".format(choice))
+    if args.affix_length < 2:
+        # affixes shorter than the server MINPREFIX (2 by default) are rejected
+        raise ValueError("--affix-length must be at least 2")
+    if args.bool_depth < 1:
+        raise ValueError("--bool-depth must be at least 1")
+    if args.bool_width < 2:
+        raise ValueError("--bool-width must be at least 2")
+    if args.bool_negation_probability < 0.0 or args.bool_negation_probability > 1.0:
+        raise ValueError("--bool-negation-probability must be within [0,1]")
+    if TAG_NEGATION_QUERY in query_choices:
+        if args.text_fields == 0 or args.tag_fields == 0:
+            raise ValueError(
+                "{} requires --text-fields and --tag-fields".format(TAG_NEGATION_QUERY)
+            )
+        if args.tag_negation_probability < 0.0 or args.tag_negation_probability > 1.0:
+            raise ValueError("--tag-negation-probability must be within [0,1]")
+    if GEOSHAPE_QUERY in query_choices and args.geoshape_fields == 0:
+        raise ValueError("{} requires --geoshape-fields".format(GEOSHAPE_QUERY))
+    if args.dialect != 0 and args.dialect not in [1, 2, 3, 4]:
+        raise ValueError("--dialect must be one of 1, 2, 3, 4")
+    if args.parameterized_queries:
+        # PARAMS are only supported from DIALECT 2 onwards
+        if args.dialect == 1:
+            raise ValueError("--parameterized-queries requires --dialect 2 or greater")
+        search_options["dialect"] = max(2, args.dialect)
+    if search_no_content and len(search_options["return_fields"]) > 0:
+        raise ValueError(
+            "--search-no-content and --return-fields are mutually exclusive"
+        )
+    use_hset = args.use_hset or args.no_index
+    output_buffer_size = parse_size(args.output_buffer_size)
+    if output_buffer_size == 0:
+        output_buffer_size = -1
+    elif output_buffer_size == 1:
+        # a buffering of 1 means line buffering on text files
+        raise ValueError("--output-buffer-size must be 0 or greater than 1")
+    if args.fsync_every < 0:
+        raise ValueError("--fsync-every can't be negative")
+    if args.no_index and alter_every > 0:
+        raise ValueError(
+            "--alter-every requires an index, so it can't be used with --no-index"
+        )
+    words_per_doc = args.words_per_doc
+    doc_prefix = args.doc_prefix
+    slot_tags = []
+    if args.target_slots < 0 or args.target_slots > CLUSTER_SLOTS:
+        raise ValueError("--target-slots must be within [0,{}]".format(CLUSTER_SLOTS))
+    if args.target_slots > 0:
+        slot_tags = get_slot_hash_tags(args.target_slots)
+    index_name = args.index_name
+    description = args.test_description
+    if target_dataset_size > 0:
+        doc_limit = 0
+        test_name = "{}-{}".format(args.target_dataset_size, args.test_name)
+    else:
+        test_name = "{}-{}".format(human_format(doc_limit), args.test_name)
+    s3_bucket_name = "benchmarks.redislabs"
+    s3_bucket_path = "redisearch/datasets/{}/".format(test_name)
+
+    benchmark_output_file = "{test_name}.{project}.commands".format(
+        test_name=test_name, project=project
+    )
+    benchmark_config_file = "{test_name}.{project}.cfg.json".format(
+        test_name=test_name, project=project
+    )
+    setup_fname = "{}.SETUP.csv".format(benchmark_output_file)
+    setup_resp_fname = "{}.SETUP.resp".format(benchmark_output_file)
+    bench_fname = "{}.BENCH.QUERY_{}.csv".format(
+        benchmark_output_file, "__".join(query_choices)
+    )
+
+    ## remove previous files if they exist
+    remove_file_if_exists(benchmark_config_file)
+    remove_file_if_exists(setup_fname)
+    remove_file_if_exists(setup_resp_fname)
+    remove_file_if_exists(bench_fname)
+
+    used_indices = [index_name]
+    if args.no_index:
+        used_indices = []
+    setup_commands = []
+    teardown_commands = []
+    key_metrics = []
+
+    add_key_metric(
+        key_metrics,
+        "setup",
+        "throughput",
+        "OverallRates.overallOpsRate",
+        "Overall writes query rate",
+        "docs/sec",
+        "numeric",
+        "higher-better",
+        1,
+    )
+    add_key_metric(
+        key_metrics,
+        "benchmark",
+        "throughput",
+        "OverallRates.overallOpsRate",
+        "Overall search query rate",
+        "docs/sec",
+        "numeric",
+        "higher-better",
+        1,
+    )
+
+    total_writes = 0
+    total_reads = 0
+    total_updates = 0
+    total_deletes = 0
+
+    json_version = "0.1"
+    benchmark_repetitions_require_teardown_and_resetup = False
+
+    print("-- Benchmark: {} -- ".format(test_name))
+    print("-- Description: {} -- ".format(description))
+
+    seed_random(args)
+
+    if discovered_schema is not None:
+        schema = discovered_schema
+    else:
+        sortable_fields = [f for f in args.sortable_fields.split(",") if f != ""]
+        schema = generate_synthetic_schema(
+            args.numeric_fields,
+            args.text_fields,
+            sortable_fields,
+            parse_text_field_weights(args.text_field_weights),
+            args.tag_fields,
+            args.geoshape_fields,
+            args.geoshape_coord_system,
+            args.with_suffix_trie,
+            missing_rate > 0.0,
+        )
+    if ISMISSING_QUERY in query_choices and len(get_indexmissing_fields(schema)) == 0:
+        raise ValueError(
+            "{} requires fields indexed with INDEXMISSING".format(ISMISSING_QUERY)
+        )
+    if SORTBY_QUERY in query_choices and len(get_sortable_fields(schema)) == 0:
+        raise ValueError("{} requires SORTABLE fields".format(SORTBY_QUERY))
+    if RANGE_SORTBY_QUERY in query_choices:
+        if len(get_sortable_numeric_fields(schema)) == 0:
+            raise ValueError(
+                "{} requires SORTABLE NUMERIC fields".format(RANGE_SORTBY_QUERY)
+            )
+        if args.range_selectivity <= 0.0 or args.range_selectivity > 1.0:
+            raise ValueError("--range-selectivity must be within ]0,1]")
+    # the timestamp field is kept apart from the schema, so that the other queries don't target it
+    index_schema = schema
+    if timestamp_options is not None:
+        timestamp_field = timestamp_options["field"]
+        if discovered_schema is not None:
+            if (
+                timestamp_field not in schema
+                or schema[timestamp_field]["type"] != NUMERIC
+            ):
+                raise ValueError(
+                    "timestamp field {} is not a NUMERIC field of the index".format(
+                        timestamp_field
+                    )
+                )
+            del schema[timestamp_field]
+        else:
+            if timestamp_field in schema:
+                raise ValueError(
+                    "timestamp field {} is already part of the schema".format(
+                        timestamp_field
+                    )
+                )
+            index_schema = dict(schema)
+            index_schema[timestamp_field] = {"type": NUMERIC, "field_options": []}
+    if args.min_doc_bytes > 0 and PADDING_FIELD in index_schema:
+        raise ValueError(
+            "--min-doc-bytes pads the documents with the {} field, which is already part of the schema".format(
+                PADDING_FIELD
+            )
+        )
+    infields = [f for f in args.infields_fields.split(",") if f != ""]
+    if INFIELDS_QUERY in query_choices:
+        if len(infields) == 0:
+            infields = [f for f, v in schema.items() if v["type"] == TEXT]
+        for f in infields:
+            if f not in schema or schema[f]["type"] != TEXT:
+                raise ValueError(
+                    "infields field {} is not a TEXT field of the schema".format(f)
+                )
+        if args.infields_count < 1 or args.infields_count > len(infields):
+            raise ValueError(
+                "--infields-count must be within [1,{}]".format(len(infields))
+            )
+    tag_values = ["tag{}".format(n) for n in range(1, args.tag_cardinality + 1)]
+    correlations = {}
+    if args.field_correlations != "":
+        correlations = parse_field_correlations(args.field_correlations, schema)
+    for f in search_options["return_fields"]:
+        if f not in index_schema:
+            raise ValueError("return field {} is not part of the schema".format(f))
+    score_field = args.score_from_field
+    if score_field != "" and (
+        score_field not in schema or schema[score_field]["type"] != NUMERIC
+    ):
+        raise ValueError(
+            "score field {} is not a NUMERIC field of the schema".format(score_field)
+        )
+    vocabulary = []
+    use_suggestions = SUGGET_QUERY in query_choices
+    alters_text = alter_every > 0 and args.alter_field_type == TEXT
+    if args.text_fields > 0 or use_suggestions or alters_text:
+        vocabulary = generate_vocabulary(args.dictionary_file, args.vocab_size)
+        print("Using a vocabulary of {} distinct words".format(len(vocabulary)))
+
+    if args.no_index:
+        print("-- skipping the ft.create and ft.drop commands given --no-index -- ")
+    elif discovered_schema is not None:
+        print(
+            "-- skipping the ft.create and ft.drop commands given the index already exists -- "
+        )
+    else:
+        print("-- generating the ft.create commands -- ")
+        ft_create_cmd = generate_ft_create_row(
+            index_name, index_schema, use_hset, doc_prefix
+        )
+        print("FT.CREATE command: {}".format(" ".join(ft_create_cmd)))
+        setup_commands.append(ft_create_cmd)
+
+        print("-- generating the ft.drop commands -- ")
+        ft_drop_cmd = generate_ft_drop_row(index_name)
+        teardown_commands.append(ft_drop_cmd)
+    if use_suggestions:
+        # the suggestion dictionary is not part of the index, so it is not dropped with it
+        teardown_commands.append(["DEL", args.suggestion_key])
+
+    print("-- generating the setup commands -- ")
+    if target_dataset_size > 0:
+        print(
+            "\t generating documents until reaching {}".format(
+                humanized_bytes(target_dataset_size)
+            )
+        )
+        progress = tqdm(unit="B", unit_scale=True, total=target_dataset_size)
+    else:
+        progress = tqdm(unit="docs", total=doc_limit)
+    setup_csvfile = SyncedOutputFile(
+        setup_fname, "w", output_buffer_size, args.fsync_every
+    )
+    setup_csv_writer = csv.writer(setup_csvfile, delimiter=",")
+    setup_respfile = None
+    if args.resp_setup:
+        # the RESP stream is self contained, so it also creates the index
+        setup_respfile = SyncedOutputFile(
+            setup_resp_fname, "wb", output_buffer_size, args.fsync_every
+        )
+        for cmd in setup_commands:
+            setup_respfile.write(encode_resp_command(cmd))
+    total_docs = 0
+    # the number of distinct document ids, given duplicates resend already inserted ids
+    distinct_docs = 0
+    total_duplicates = 0
+    total_alters = 0
+    total_dataset_size = 0
+    while (doc_limit > 0 and total_docs < doc_limit) or (
+        target_dataset_size > 0 and total_dataset_size < target_dataset_size
+    ):
+        duplicate = (
+            duplicate_rate > 0.0
+            and distinct_docs > 0
+            and random.random() < duplicate_rate
+        )
+        if duplicate:
+            doc_n = random.randrange(distinct_docs)
+            total_duplicates = total_duplicates + 1
+        else:
+            doc_n = distinct_docs
+            distinct_docs = distinct_docs + 1
+        doc_id = format_doc_id(doc_prefix, doc_n, slot_tags)
+        doc_words = get_doc_words(
+            words_per_doc, args.doc_size_distribution, args.doc_size_sigma
+        )
+        doc = generate_doc(
+            schema,
+            numeric_range,
+            vocabulary,
+            doc_words,
+            tag_values,
+            geoshape_options,
+            args.shuffle_fields,
+            missing_rate,
+            correlations,
+        )
+        add_doc_timestamp(doc, doc_n, timestamp_options)
+        pad_doc(doc_id, doc, args.min_doc_bytes)
+        doc_size = estimate_doc_size(doc_id, doc)
+        cmd = generate_write_row(
+            use_hset,
+            index_name,
+            doc_id,
+            doc,
+            "SETUP_WRITE",
+            "S1",
+            duplicate,
+            get_doc_score(doc, score_field, numeric_range),
+        )
+        setup_csv_writer.writerow(cmd)
+        if setup_respfile is not None:
+            # skip the command type, query group and key position columns
+            setup_respfile.write(encode_resp_command(cmd[3:]))
+        total_docs = total_docs + 1
+        if alter_every > 0 and total_docs % alter_every == 0:
+            total_alters = total_alters + 1
+            field = "alter{}".format(total_alters)
+            schema[field] = {"type": args.alter_field_type, "field_options": []}
+            cmd = generate_alter_row(index_name, field, args.alter_field_type)
+            setup_csv_writer.writerow(cmd)
+            if setup_respfile is not None:
+                setup_respfile.write(encode_resp_command(cmd[3:]))
+        total_dataset_size = total_dataset_size + doc_size
+        if target_dataset_size > 0:
+            progress.update(doc_size)
+        else:
+            progress.update()
+    progress.close()
+    total_suggestions = 0
+    if use_suggestions:
+        print(
+            "\t adding the {} vocabulary words to the {} suggestion dictionary".format(
+                len(vocabulary), args.suggestion_key
+            )
+        )
+        for word in vocabulary:
+            cmd = generate_sugadd_row(args.suggestion_key, word, random.randint(1, 100))
+            setup_csv_writer.writerow(cmd)
+            if setup_respfile is not None:
+                setup_respfile.write(encode_resp_command(cmd[3:]))
+            total_suggestions = total_suggestions + 1
+    setup_csvfile.close()
+    if setup_respfile is not None:
+        setup_respfile.close()
+        print(
+            "Wrote the setup commands as a RESP stream to {}".format(setup_resp_fname)
+        )
+    print(
+        "Generated {} docs with an estimated dataset size of {}".format(
+            total_docs, humanized_bytes(total_dataset_size)
+        )
+    )
+    if total_duplicates > 0:
+        print(
+            "\t {} of which resend an already inserted document id".format(
+                total_duplicates
+            )
+        )
+    if total_alters > 0:
+        print(
+            "\t with {} FT.ALTER commands adding {} fields along the way".format(
+                total_alters, args.alter_field_type
+            )
+        )
+
+    print("-- generating {} benchmark commands -- ".format(total_benchmark_commands))
+    print("\t saving to {}".format(bench_fname))
+    progress = tqdm(unit="commands", total=total_benchmark_commands)
+    bench_csvfile = SyncedOutputFile(
+        bench_fname, "w", output_buffer_size, args.fsync_every
+    )
+    bench_csv_writer = csv.writer(bench_csvfile, delimiter=",")
+    # the ids of the documents inserted so far ( and not deleted ), that can be targeted by updates and deletes
+    if id_space == 0:
+        id_space = distinct_docs
+    live_doc_ids = list(range(0, id_space))
+    next_doc_id = max(id_space, distinct_docs)
+    # the latest document written so far, whose timestamp ends the recent time windows
+    latest_doc_n = distinct_docs - 1
+    dependent_read = None
+    for _ in range(0, total_benchmark_commands):
+        if dependent_read is not None:
+            total_reads = total_reads + 1
+            bench_csv_writer.writerow(dependent_read)
+            dependent_read = None
+            progress.update()
+            continue
+        op = random.choices(
+            ["read", "write", "update", "delete"],
+            weights=[read_ratio, write_ratio, update_ratio, delete_ratio],
+        )[0]
+        if op == "delete" and len(live_doc_ids) > 0:
+            doc_id = format_doc_id(
+                doc_prefix,
+                live_doc_ids.pop(pick_doc_id_pos(live_doc_ids, id_access)),
+                slot_tags,
+            )
+            cmd = generate_delete_row(use_hset, index_name, doc_id)
+            total_deletes = total_deletes + 1
+            bench_csv_writer.writerow(cmd)
+            progress.update()
+            continue
+        if op == "write" or (op == "update" and len(live_doc_ids) > 0):
+            doc_words = get_doc_words(
+                words_per_doc, args.doc_size_distribution, args.doc_size_sigma
+            )
+            doc = generate_doc(
+                schema,
+                numeric_range,
+                vocabulary,
+                doc_words,
+                tag_values,
+                geoshape_options,
+                args.shuffle_fields,
+                missing_rate,
+                correlations,
+            )
+            if op == "write":
+                duplicate = (
+                    duplicate_rate > 0.0
+                    and len(live_doc_ids) > 0
+                    and random.random() < duplicate_rate
+                )
+                if duplicate:
+                    doc_n = random.choice(live_doc_ids)
+                else:
+                    doc_n = next_doc_id
+                    live_doc_ids.append(next_doc_id)
+                    next_doc_id = next_doc_id + 1
+                    latest_doc_n = max(latest_doc_n, doc_n)
+                doc_id = format_doc_id(doc_prefix, doc_n, slot_tags)
+                add_doc_timestamp(doc, doc_n, timestamp_options)
+                pad_doc(doc_id, doc, args.min_doc_bytes)
+                cmd = generate_write_row(
+                    use_hset,
+                    index_name,
+                    doc_id,
+                    doc,
+                    "WRITE",
+                    "W1",
+                    duplicate,
+                    get_doc_score(doc, score_field, numeric_range),
+                )
+                total_writes = total_writes + 1
+            else:
+                doc_n = live_doc_ids[pick_doc_id_pos(live_doc_ids, id_access)]
+                doc_id = format_doc_id(doc_prefix, doc_n, slot_tags)
+                add_doc_timestamp(doc, doc_n, timestamp_options)
+                pad_doc(doc_id, doc, args.min_doc_bytes)
+                cmd = generate_write_row(
+                    use_hset,
+                    index_name,
+                    doc_id,
+                    doc,
+                    "UPDATE",
+                    "U1",
+                    True,
+                    get_doc_score(doc, score_field, numeric_range),
+                )
+                total_updates = total_updates + 1
+            bench_csv_writer.writerow(cmd)
+            if (
+                read_your_writes_probability > 0.0
+                and random.random() < read_your_writes_probability
+            ):
+                dependent_read = generate_dependent_read_row(
+                    use_hset, index_name, doc_id
+                )
+            progress.update()
+            continue
+        choice = random.choices(query_choices)[0]
+        if stopword_query_rate > 0.0 and random.random() < stopword_query_rate:
+            choice = STOPWORD_QUERY
+        if choice == STOPWORD_QUERY:
+            cmd = generate_stopword_row(index_name, search_options)
+        elif choice == SEARCH_NUMERIC_RANGE:
+            cmd = generate_numeric_range_row(
+                index_name, schema, numeric_range, search_options
+            )
+        elif choice == SIMPLE_WORD_QUERY:
+            cmd = generate_ft_search_row(
+                index_name,
+                SIMPLE_WORD_QUERY,
+                random.choice(vocabulary),
+                search_options,
+            )
+        elif choice == SUGGET_QUERY:
+            cmd = generate_sugget_row(
+                args.suggestion_key,
+                random.choice(vocabulary),
+                args.suggestion_prefix_length,
+            )
+        elif choice == SORTBY_QUERY:
+            # sort the documents matching a term, or all documents when there are no TEXT fields
+            query = "*"
+            if len(vocabulary) > 0:
+                query = random.choice(vocabulary)
+            cmd = generate_sortby_row(
+                index_name, schema, query, args.sort_limit, search_options
+            )
+        elif choice == RANGE_SORTBY_QUERY:
+            cmd = generate_range_sortby_row(
+                index_name,
+                schema,
+                numeric_range,
+                args.range_selectivity,
+                args.sort_limit,
+                search_options,
+            )
+        elif choice == TAG_NEGATION_QUERY:
+            cmd = generate_tag_negation_row(
+                index_name,
+                schema,
+                random.choice(vocabulary),
+                tag_values,
+                args.tag_negation_probability,
+                search_options,
+            )
+        elif choice == SUFFIX_QUERY or choice == CONTAINS_QUERY:
+            cmd = generate_affix_row(
+                index_name,
+                choice,
+                random.choice(vocabulary),
+                args.affix_length,
+                search_options,
+            )
+        elif choice == BOOLEAN_QUERY:
+            cmd = generate_boolean_row(
+                index_name,
+                vocabulary,
+                args.bool_depth,
+                args.bool_width,
+                args.bool_negation_probability,
+                search_options,
+            )
+        elif choice == APPLY_FILTER_QUERY:
+            cmd = generate_apply_filter_row(
+                index_name,
+                schema,
+                numeric_range,
+                args.apply_expressions,
+                args.filter_predicates,
+                search_options,
+            )
+        elif choice == RECENT_WINDOW_QUERY:
+            cmd = generate_recent_window_row(
+                index_name, latest_doc_n, timestamp_options, search_options
+            )
+        elif choice == INFIELDS_QUERY:
+            cmd = generate_infields_row(
+                index_name,
+                random.choice(vocabulary),
+                infields,
+                args.infields_count,
+                search_options,
+            )
+        elif choice == ISMISSING_QUERY:
+            cmd = generate_ismissing_row(index_name, schema, search_options)
+        elif choice == GEOSHAPE_QUERY:
+            cmd = generate_geoshape_row(
+                index_name, schema, geoshape_options, search_options
+            )
+        elif choice == HYBRID_QUERY:
+            cmd = generate_hybrid_row(
+                index_name,
+                schema,
+                numeric_range,
+                vocabulary,
+                tag_values,
+                hybrid_probabilities,
+                search_options,
+            )
+        total_reads = total_reads + 1
+        bench_csv_writer.writerow(cmd)
+        progress.update()
+    progress.close()
+    bench_csvfile.close()
+
+    total_setup_commands = total_docs + total_alters + total_suggestions
+    total_commands = total_setup_commands + total_benchmark_commands
+
+    deployment_requirements = init_deployment_requirement()
+    add_deployment_requirements_redis_server_module(
+        deployment_requirements, "search", {}
+    )
+    add_deployment_requirements_utilities(
+        deployment_requirements, "ftsb_redisearch", {}
+    )
+    add_deployment_requirements_benchmark_tool(
+        deployment_requirements, "ftsb_redisearch"
+    )
+
+    run_stages = ["setup", "benchmark"]
+    with open(benchmark_config_file, "w") as setupf:
+        setup_json = generate_setup_json(
+            json_version,
+            project,
+            use_case_specific_arguments,
+            test_name,
+            description,
+            run_stages,
+            deployment_requirements,
+            key_metrics,
+            {},
+            setup_commands,
+            teardown_commands,
+            used_indices,
+            total_commands,
+            total_setup_commands,
+            total_benchmark_commands,
+            distinct_docs,
+            total_writes,
+            total_updates,
+            total_reads,
+            total_deletes,
+            benchmark_repetitions_require_teardown_and_resetup,
+            ["setup"],
+            ["benchmark"],
+        )
+        json.dump(setup_json, setupf, indent=2)
+
+    if args.upload_artifacts_s3:
+        artifacts = [benchmark_config_file, setup_fname, bench_fname]
+        if args.resp_setup:
+            artifacts.append(setup_resp_fname)
+        upload_dataset_artifacts_s3(s3_bucket_name, s3_bucket_path, artifacts)
+
+    print("############################################")
+    print("All artifacts generated.")